@@ -0,0 +1,345 @@
+package cookiejar
+
+import (
+	"cmp"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/bool64/ctxd"
+	"github.com/spf13/afero"
+)
+
+// AuditOp is the operation of an audit record.
+type AuditOp string
+
+const (
+	// AuditSet is the creation of a cookie.
+	AuditSet AuditOp = "set"
+	// AuditChange is the change of the value or the attributes of a cookie.
+	AuditChange AuditOp = "change"
+	// AuditRemove is the removal of a cookie, e.g. by a Set-Cookie header with a past expiry.
+	AuditRemove AuditOp = "remove"
+	// AuditExpire is the removal of an expired cookie.
+	AuditExpire AuditOp = "expire"
+)
+
+// AuditRecord is a change of a cookie. It never contains the value of the cookie.
+type AuditRecord struct {
+	Time time.Time `json:"time"`
+	Op   AuditOp   `json:"op"`
+	// ID is the domain;path;name triple of the cookie.
+	ID string `json:"id"`
+	// URL is the scheme, host and path of the URL of the request or the response that made the change, without its
+	// user info, query and fragment. It is empty for the changes that are not made by a request, e.g. ImportEntries,
+	// RemoveExpired or the cookies dropped when loading.
+	URL string `json:"url,omitempty"`
+	// ValueHash is the HMAC-SHA256 of the value in hexadecimal, keyed with the audit hash key of the jar. It is the
+	// value before the removal for AuditRemove and AuditExpire.
+	ValueHash string `json:"value_hash"`
+}
+
+// AuditSink receives the audit records of a jar.
+//
+// The records are sent with the lock of the jar held, a sink must not use the jar.
+type AuditSink interface {
+	Audit(r AuditRecord) error
+}
+
+// auditSource returns the URL of the audit records of the changes made by a request to u, see AuditRecord.URL.
+func (j *Jar) auditSource(u *url.URL) string {
+	if j.audit == nil {
+		return ""
+	}
+
+	return (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}).String()
+}
+
+// auditRecords returns the changes from before to after, ordered by id. The last access time and the sequence number
+// are not changes.
+func (j *Jar) auditRecords(before, after map[string]entry, source string, now time.Time) []AuditRecord {
+	var records []AuditRecord
+
+	record := func(op AuditOp, id string, e entry) {
+		records = append(records, j.auditRecord(op, id, source, e, now))
+	}
+
+	for id, e := range after {
+		old, ok := before[id]
+
+		switch {
+		case !ok:
+			record(AuditSet, id, e)
+
+		case j.changed(old, e):
+			record(AuditChange, id, e)
+		}
+	}
+
+	for id, e := range before {
+		if _, ok := after[id]; ok {
+			continue
+		}
+
+		if e.Persistent && !e.Expires.After(now) {
+			record(AuditExpire, id, e)
+		} else {
+			record(AuditRemove, id, e)
+		}
+	}
+
+	slices.SortFunc(records, func(a, b AuditRecord) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	return records
+}
+
+// auditRecord returns the record of the change of e.
+func (j *Jar) auditRecord(op AuditOp, id, source string, e entry, now time.Time) AuditRecord {
+	return AuditRecord{
+		Time:      now,
+		Op:        op,
+		ID:        id,
+		URL:       source,
+		ValueHash: j.valueHash(e),
+	}
+}
+
+// auditExpired returns the records of the expired entries removed by an ExpiredRemover, ordered by id.
+func (j *Jar) auditExpired(removed map[string]map[string]Entry, now time.Time) []AuditRecord {
+	var records []AuditRecord
+
+	for _, e := range sortedEntries(removed) {
+		records = append(records, j.auditRecord(AuditExpire, e.ID(), "", importEntry(e), now))
+	}
+
+	slices.SortFunc(records, func(a, b AuditRecord) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	return records
+}
+
+// auditDropped audits the removal of the loaded entries that the public suffix list makes illegal, see RekeyReport.
+// The caller must hold j.mu.
+func (j *Jar) auditDropped(loaded map[string]map[string]Entry, dropped []RekeyedEntry) {
+	if j.audit == nil {
+		return
+	}
+
+	now := time.Now()
+	records := make([]AuditRecord, 0, len(dropped))
+
+	for _, d := range dropped {
+		if e, ok := loaded[d.OldKey][d.ID]; ok {
+			records = append(records, j.auditRecord(AuditRemove, d.ID, "", importEntry(e), now))
+		}
+	}
+
+	j.emitAudit(records)
+}
+
+// emitAudit sends the records to the audit sink. The errors are passed to the StoreErrorHandler.
+func (j *Jar) emitAudit(records []AuditRecord) {
+	for _, r := range records {
+		if err := j.audit.Audit(r); err != nil {
+			j.storeError(err)
+		}
+	}
+}
+
+// changed reports whether the value or an attribute of the cookie changed. The sealed values are compared unsealed.
+func (j *Jar) changed(old, e entry) bool {
+	if old.Value != e.Value && j.valueHash(old) != j.valueHash(e) {
+		return true
+	}
+
+	return old.Quoted != e.Quoted || old.SameSite != e.SameSite || old.Secure != e.Secure ||
+		old.HttpOnly != e.HttpOnly || old.Persistent != e.Persistent || !old.Expires.Equal(e.Expires) ||
+		old.Partitioned != e.Partitioned || old.PartitionKey != e.PartitionKey
+}
+
+// valueHash returns the HMAC-SHA256 of the unsealed value of e. A value that cannot be unsealed is hashed as it is
+// stored.
+func (j *Jar) valueHash(e entry) string {
+	value, err := j.sealer.unseal(e)
+	if err != nil {
+		value = e.Value
+	}
+
+	if j.auditKey == nil {
+		// A random key for the life of the jar, as the one of a redacted export.
+		j.auditKey = make([]byte, sha256.Size)
+
+		_, _ = rand.Read(j.auditKey) //nolint: errcheck
+	}
+
+	mac := hmac.New(sha256.New, j.auditKey)
+
+	_, _ = io.WriteString(mac, value) //nolint: errcheck
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+var _ AuditSink = (*AuditFile)(nil)
+
+// AuditFile is an AuditSink that appends the records to a file as JSON lines. The file is rotated when it reaches its
+// maximum size: "audit.log" is renamed to "audit.log.1", "audit.log.1" to "audit.log.2", and so on, and the oldest
+// file is removed.
+type AuditFile struct {
+	fs         afero.Fs
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	mu   sync.Mutex
+	f    afero.File
+	size int64
+}
+
+// NewAuditFile returns an AuditSink that appends the records to path on fs. The file is rotated before it grows past
+// maxSize bytes and maxBackups rotated files are kept. A maxSize of 0 never rotates the file.
+func NewAuditFile(fs afero.Fs, path string, maxSize int64, maxBackups int) *AuditFile {
+	return &AuditFile{
+		fs:         fs,
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+	}
+}
+
+// Audit appends r to the file.
+func (a *AuditFile) Audit(r AuditRecord) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	line = append(line, '\n')
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.f == nil {
+		if err := a.open(); err != nil {
+			return err
+		}
+	}
+
+	if a.maxSize > 0 && a.size > 0 && a.size+int64(len(line)) > a.maxSize {
+		if err := a.rotate(); err != nil {
+			return fmt.Errorf("could not rotate audit file: %w", err)
+		}
+	}
+
+	n, err := a.f.Write(line)
+	a.size += int64(n)
+
+	return err
+}
+
+// Close syncs and closes the file.
+func (a *AuditFile) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.f == nil {
+		return nil
+	}
+
+	err := errors.Join(a.f.Sync(), a.f.Close())
+	a.f = nil
+
+	return err
+}
+
+func (a *AuditFile) open() error {
+	f, err := a.fs.OpenFile(a.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, permReadonly)
+	if err != nil {
+		return fmt.Errorf("could not open audit file: %w", err)
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close() //nolint: errcheck
+
+		return fmt.Errorf("could not open audit file: %w", err)
+	}
+
+	a.f = f
+	a.size = fi.Size()
+
+	return nil
+}
+
+func (a *AuditFile) rotate() error {
+	if err := errors.Join(a.f.Sync(), a.f.Close()); err != nil {
+		return err
+	}
+
+	a.f = nil
+
+	if a.maxBackups <= 0 {
+		if err := a.fs.Remove(a.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+
+		return a.open()
+	}
+
+	if err := a.fs.Remove(a.backupPath(a.maxBackups)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	for i := a.maxBackups - 1; i >= 1; i-- {
+		if err := a.fs.Rename(a.backupPath(i), a.backupPath(i+1)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	if err := a.fs.Rename(a.path, a.backupPath(1)); err != nil {
+		return err
+	}
+
+	return a.open()
+}
+
+func (a *AuditFile) backupPath(i int) string {
+	return fmt.Sprintf("%s.%d", a.path, i)
+}
+
+var _ AuditSink = (*auditLogger)(nil)
+
+// auditLogger is an AuditSink that logs the records.
+type auditLogger struct {
+	logger ctxd.Logger
+}
+
+// NewAuditLogger returns an AuditSink that logs the records with logger at the info level.
+func NewAuditLogger(logger ctxd.Logger) AuditSink {
+	return auditLogger{logger: logger}
+}
+
+func (a auditLogger) Audit(r AuditRecord) error {
+	a.logger.Info(context.Background(), "cookie "+string(r.Op),
+		"cookie.time", r.Time,
+		"cookie.op", r.Op,
+		"cookie.id", r.ID,
+		"cookie.url", r.URL,
+		"cookie.value_hash", r.ValueHash,
+	)
+
+	return nil
+}
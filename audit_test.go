@@ -0,0 +1,286 @@
+package cookiejar_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bool64/ctxd"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.nhat.io/cookiejar"
+)
+
+// auditRecorder is a cookiejar.AuditSink in a slice.
+type auditRecorder struct {
+	mu      sync.Mutex
+	records []cookiejar.AuditRecord
+}
+
+func (r *auditRecorder) Audit(record cookiejar.AuditRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	record.Time = time.Time{}
+	r.records = append(r.records, record)
+
+	return nil
+}
+
+func (r *auditRecorder) take() []cookiejar.AuditRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	records := r.records
+	r.records = nil
+
+	return records
+}
+
+var auditHashKey = []byte("audit-hash-key")
+
+func hmacHex(s string) string {
+	mac := hmac.New(sha256.New, auditHashKey)
+	mac.Write([]byte(s))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestJar_Audit(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario string
+		sealer   *cookiejar.Sealer
+	}{
+		{scenario: "plain"},
+		{scenario: "sealed", sealer: cookiejar.NewSealer(cookiejar.RawKey(newKey), cookiejar.SealRule{Name: "id"})},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			sink := &auditRecorder{}
+
+			jar, err := cookiejar.New(&cookiejar.Options{Audit: sink, AuditHashKey: auditHashKey, Sealer: tc.sealer})
+			require.NoError(t, err)
+
+			// The user info, query and fragment are not recorded.
+			u := &url.URL{
+				Scheme:   "https",
+				User:     url.UserPassword("alice", "secret"),
+				Host:     "example.com",
+				Path:     "/login",
+				RawQuery: "token=secret",
+				Fragment: "secret",
+			}
+
+			jar.SetCookies(u, []*http.Cookie{{Name: "id", Value: "42"}})
+
+			expected := []cookiejar.AuditRecord{
+				{Op: cookiejar.AuditSet, ID: "example.com;/;id", URL: "https://example.com/login", ValueHash: hmacHex("42")},
+			}
+
+			assert.Equal(t, expected, sink.take())
+
+			// Reading and setting the same cookie again are not changes.
+			jar.Cookies(u)
+			jar.SetCookies(u, []*http.Cookie{{Name: "id", Value: "42"}})
+
+			assert.Empty(t, sink.take())
+
+			jar.SetCookies(u, []*http.Cookie{{Name: "id", Value: "43"}})
+			jar.SetCookies(u, []*http.Cookie{{Name: "id", Value: "43", Secure: true}})
+
+			expected = []cookiejar.AuditRecord{
+				{Op: cookiejar.AuditChange, ID: "example.com;/;id", URL: "https://example.com/login", ValueHash: hmacHex("43")},
+				{Op: cookiejar.AuditChange, ID: "example.com;/;id", URL: "https://example.com/login", ValueHash: hmacHex("43")},
+			}
+
+			assert.Equal(t, expected, sink.take())
+
+			jar.SetCookies(u, []*http.Cookie{{Name: "id", MaxAge: -1}})
+
+			expected = []cookiejar.AuditRecord{
+				{Op: cookiejar.AuditRemove, ID: "example.com;/;id", URL: "https://example.com/login", ValueHash: hmacHex("43")},
+			}
+
+			assert.Equal(t, expected, sink.take())
+
+			jar.ImportEntries(cookiejar.Entry{
+				Name:       "id",
+				Value:      "44",
+				Domain:     "example.com",
+				Path:       "/",
+				HostOnly:   true,
+				Persistent: true,
+				Expires:    time.Now().Add(50 * time.Millisecond),
+			})
+
+			expected = []cookiejar.AuditRecord{
+				{Op: cookiejar.AuditSet, ID: "example.com;/;id", ValueHash: hmacHex("44")},
+			}
+
+			assert.Equal(t, expected, sink.take())
+
+			time.Sleep(100 * time.Millisecond)
+
+			assert.Empty(t, jar.Cookies(u))
+
+			expected = []cookiejar.AuditRecord{
+				{Op: cookiejar.AuditExpire, ID: "example.com;/;id", URL: "https://example.com/login", ValueHash: hmacHex("44")},
+			}
+
+			assert.Equal(t, expected, sink.take())
+		})
+	}
+}
+
+func TestAuditFile(t *testing.T) {
+	t.Parallel()
+
+	const path = "/var/log/cookies.audit"
+
+	fs := afero.NewMemMapFs()
+	record := cookiejar.AuditRecord{
+		Time:      time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Op:        cookiejar.AuditSet,
+		ID:        "example.com;/;id",
+		URL:       "https://example.com/",
+		ValueHash: hmacHex("42"),
+	}
+
+	line := `{"time":"2024-01-02T03:04:05Z","op":"set","id":"example.com;/;id","url":"https://example.com/",` +
+		`"value_hash":"` + hmacHex("42") + `"}` + "\n"
+
+	// Two records per file.
+	sink := cookiejar.NewAuditFile(fs, path, int64(2*len(line)), 2)
+
+	for range 7 {
+		require.NoError(t, sink.Audit(record))
+	}
+
+	require.NoError(t, sink.Close())
+
+	for _, tc := range []struct {
+		path     string
+		expected string
+	}{
+		{path: path, expected: line},
+		{path: path + ".1", expected: strings.Repeat(line, 2)},
+		{path: path + ".2", expected: strings.Repeat(line, 2)},
+	} {
+		data, err := afero.ReadFile(fs, tc.path)
+		require.NoError(t, err, tc.path)
+
+		assert.Equal(t, tc.expected, string(data), tc.path)
+	}
+
+	_, err := fs.Stat(path + ".3")
+	require.Error(t, err)
+
+	// The file is appended after it is closed.
+	require.NoError(t, sink.Audit(record))
+	require.NoError(t, sink.Close())
+
+	data, err := afero.ReadFile(fs, path)
+	require.NoError(t, err)
+
+	assert.Equal(t, strings.Repeat(line, 2), string(data))
+}
+
+func TestPersistentJar_Audit(t *testing.T) {
+	t.Parallel()
+
+	logger := &ctxd.LoggerMock{}
+
+	j := cookiejar.NewPersistentJar(
+		cookiejar.WithFs(afero.NewMemMapFs()),
+		cookiejar.WithAudit(cookiejar.NewAuditLogger(logger)),
+		cookiejar.WithAuditHashKey(auditHashKey),
+	)
+
+	j.SetCookies(&url.URL{Scheme: "https", Host: "example.com", Path: "/"}, []*http.Cookie{{Name: "id", Value: "secret"}})
+
+	require.Len(t, logger.LoggedEntries, 1)
+
+	entry := logger.LoggedEntries[0]
+
+	assert.Equal(t, "info", entry.Level)
+	assert.Equal(t, "cookie set", entry.Message)
+	assert.Equal(t, "example.com;/;id", entry.Data["cookie.id"])
+	assert.Equal(t, "https://example.com/", entry.Data["cookie.url"])
+	assert.Equal(t, hmacHex("secret"), entry.Data["cookie.value_hash"])
+
+	for _, v := range entry.Data {
+		assert.NotEqual(t, "secret", v)
+	}
+}
+
+func TestJar_Audit_RandomHashKey(t *testing.T) {
+	t.Parallel()
+
+	u := &url.URL{Scheme: "https", Host: "example.com", Path: "/"}
+	sinks := []*auditRecorder{{}, {}}
+
+	for _, sink := range sinks {
+		jar, err := cookiejar.New(&cookiejar.Options{Audit: sink})
+		require.NoError(t, err)
+
+		jar.SetCookies(u, []*http.Cookie{{Name: "id", Value: "42"}})
+		jar.SetCookies(u, []*http.Cookie{{Name: "id", MaxAge: -1}})
+	}
+
+	first, second := sinks[0].take(), sinks[1].take()
+
+	require.Len(t, first, 2)
+	require.Len(t, second, 2)
+
+	// The key is the same for the life of the jar, and different for every jar.
+	assert.Equal(t, first[0].ValueHash, first[1].ValueHash)
+	assert.NotEqual(t, first[0].ValueHash, second[0].ValueHash)
+	assert.NotEqual(t, hmacHex("42"), first[0].ValueHash)
+}
+
+func TestPersistentJar_Audit_Rekey(t *testing.T) {
+	t.Parallel()
+
+	const path = "/tmp/cookies.txt"
+
+	fs := afero.NewMemMapFs()
+	expires := time.Now().Add(time.Hour)
+
+	// A domain cookie for a public suffix is dropped when the file is loaded.
+	require.NoError(t, afero.WriteFile(fs, path, []byte(
+		".co.uk\tTRUE\t/\tFALSE\t"+strconv.FormatInt(expires.Unix(), 10)+"\tid\t42\n",
+	), 0o600))
+
+	sink := &auditRecorder{}
+
+	j := cookiejar.NewPersistentJar(
+		cookiejar.WithFs(fs),
+		cookiejar.WithFilePath(path),
+		cookiejar.WithSerDer(cookiejar.NewNetscapeSerDer()),
+		cookiejar.WithPublicSuffixList(suffixList{"co.uk"}),
+		cookiejar.WithAudit(sink),
+		cookiejar.WithAuditHashKey(auditHashKey),
+	)
+
+	assert.Empty(t, j.Entries())
+
+	expected := []cookiejar.AuditRecord{
+		{Op: cookiejar.AuditRemove, ID: "co.uk;/;id", ValueHash: hmacHex("42")},
+	}
+
+	assert.Equal(t, expected, sink.take())
+}
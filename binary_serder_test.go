@@ -0,0 +1,296 @@
+package cookiejar_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"go.nhat.io/cookiejar"
+)
+
+func binarySerDerEntries() map[string]map[string]cookiejar.Entry {
+	return map[string]map[string]cookiejar.Entry{
+		"example.com": {
+			"example.com;/;id": {
+				Name:         "id",
+				Value:        `"42"`,
+				Quoted:       true,
+				Domain:       "example.com",
+				Path:         "/",
+				SameSite:     "SameSite=Lax",
+				Secure:       true,
+				HttpOnly:     true,
+				Persistent:   true,
+				HostOnly:     true,
+				Partitioned:  true,
+				PartitionKey: "https://example.org",
+				Expires:      time.Date(9999, 12, 31, 23, 59, 59, 999, time.UTC),
+				Creation:     time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC),
+				LastAccess:   time.Date(1969, 12, 31, 23, 59, 59, 500, time.UTC),
+				SeqNum:       7,
+			},
+			"example.com;/;session": {
+				Name:   "session",
+				Domain: "example.com",
+				Path:   "/",
+			},
+		},
+		"example.org": {
+			"example.org;/docs;lang": {
+				Name:   "lang",
+				Value:  "en",
+				Domain: "example.org",
+				Path:   "/docs",
+			},
+		},
+	}
+}
+
+func TestBinarySerDer_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario string
+		serder   cookiejar.EntrySerDer
+	}{
+		{scenario: "gob", serder: cookiejar.NewGobSerDer()},
+		{scenario: "cbor", serder: cookiejar.NewCBORSerDer()},
+		{scenario: "protobuf", serder: cookiejar.NewProtobufSerDer()},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			entries := binarySerDerEntries()
+
+			var buf bytes.Buffer
+
+			require.NoError(t, tc.serder.Serialize(&buf, entries))
+
+			actual, err := tc.serder.Deserialize(&buf)
+			require.NoError(t, err)
+
+			assert.Equal(t, entries, actual)
+		})
+	}
+}
+
+func TestProtobufSerDer_Deterministic(t *testing.T) {
+	t.Parallel()
+
+	s := cookiejar.NewProtobufSerDer()
+
+	var first, second bytes.Buffer
+
+	require.NoError(t, s.Serialize(&first, binarySerDerEntries()))
+	require.NoError(t, s.Serialize(&second, binarySerDerEntries()))
+
+	assert.Equal(t, first.Bytes(), second.Bytes())
+}
+
+func TestProtobufSerDer_UnknownFields(t *testing.T) {
+	t.Parallel()
+
+	s := cookiejar.NewProtobufSerDer()
+
+	var buf bytes.Buffer
+
+	require.NoError(t, s.Serialize(&buf, binarySerDerEntries()))
+
+	// A field added by a newer version of the schema is skipped.
+	b := protowire.AppendTag(nil, 99, protowire.BytesType)
+	b = protowire.AppendString(b, "unknown")
+	b = append(b, buf.Bytes()...)
+
+	actual, err := s.Deserialize(bytes.NewReader(b))
+	require.NoError(t, err)
+
+	assert.Equal(t, binarySerDerEntries(), actual)
+}
+
+func TestProtobufSerDer_Corrupt(t *testing.T) {
+	t.Parallel()
+
+	actual, err := cookiejar.NewProtobufSerDer().Deserialize(bytes.NewReader([]byte{0x0a, 0xff}))
+
+	require.Error(t, err)
+	assert.Nil(t, actual)
+}
+
+func TestProtobufSerDer_Schema(t *testing.T) {
+	t.Parallel()
+
+	jar := protoJarDescriptor(t)
+	s := cookiejar.NewProtobufSerDer()
+
+	t.Run("serialize", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+
+		require.NoError(t, s.Serialize(&buf, binarySerDerEntries()))
+
+		msg := dynamicpb.NewMessage(jar)
+
+		require.NoError(t, proto.Unmarshal(buf.Bytes(), msg))
+
+		assert.Equal(t, binarySerDerEntries(), entriesFromProto(t, msg))
+	})
+
+	t.Run("deserialize", func(t *testing.T) {
+		t.Parallel()
+
+		data, err := proto.MarshalOptions{Deterministic: true}.Marshal(entriesToProto(jar, binarySerDerEntries()))
+		require.NoError(t, err)
+
+		actual, err := s.Deserialize(bytes.NewReader(data))
+		require.NoError(t, err)
+
+		assert.Equal(t, binarySerDerEntries(), actual)
+	})
+}
+
+// protoJarDescriptor compiles proto/cookiejar.proto and returns the descriptor of its Jar message.
+func protoJarDescriptor(t *testing.T) protoreflect.MessageDescriptor {
+	t.Helper()
+
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{ImportPaths: []string{"proto"}}),
+	}
+
+	files, err := compiler.Compile(context.Background(), "cookiejar.proto")
+	require.NoError(t, err)
+
+	md := files[0].Messages().ByName("Jar")
+	require.NotNil(t, md)
+
+	return md
+}
+
+// entriesFromProto reads the entries from a dynamic Jar message, by the names of the fields of the schema.
+func entriesFromProto(t *testing.T, jar protoreflect.Message) map[string]map[string]cookiejar.Entry {
+	t.Helper()
+
+	entries := make(map[string]map[string]cookiejar.Entry)
+
+	assert.Empty(t, jar.GetUnknown())
+
+	jar.Get(protoField(jar, "sites")).Map().Range(func(key protoreflect.MapKey, v protoreflect.Value) bool {
+		site := v.Message()
+		entries[key.String()] = make(map[string]cookiejar.Entry)
+
+		assert.Empty(t, site.GetUnknown())
+
+		site.Get(protoField(site, "entries")).Map().Range(func(id protoreflect.MapKey, v protoreflect.Value) bool {
+			m := v.Message()
+
+			assert.Empty(t, m.GetUnknown())
+
+			get := func(name protoreflect.Name) protoreflect.Value {
+				return m.Get(protoField(m, name))
+			}
+
+			entries[key.String()][id.String()] = cookiejar.Entry{
+				Name:         get("name").String(),
+				Value:        get("value").String(),
+				Quoted:       get("quoted").Bool(),
+				Domain:       get("domain").String(),
+				Path:         get("path").String(),
+				SameSite:     get("same_site").String(),
+				Secure:       get("secure").Bool(),
+				HttpOnly:     get("http_only").Bool(),
+				Persistent:   get("persistent").Bool(),
+				HostOnly:     get("host_only").Bool(),
+				Partitioned:  get("partitioned").Bool(),
+				PartitionKey: get("partition_key").String(),
+				Expires:      timeFromProto(m, "expires"),
+				Creation:     timeFromProto(m, "creation"),
+				LastAccess:   timeFromProto(m, "last_access"),
+				SeqNum:       get("seq_num").Uint(),
+			}
+
+			return true
+		})
+
+		return true
+	})
+
+	return entries
+}
+
+// entriesToProto writes the entries to a dynamic Jar message, by the names of the fields of the schema.
+func entriesToProto(jar protoreflect.MessageDescriptor, entries map[string]map[string]cookiejar.Entry) *dynamicpb.Message {
+	msg := dynamicpb.NewMessage(jar)
+	sites := msg.Mutable(protoField(msg, "sites")).Map()
+
+	for key, submap := range entries {
+		site := sites.Mutable(protoreflect.ValueOfString(key).MapKey()).Message()
+		siteEntries := site.Mutable(protoField(site, "entries")).Map()
+
+		for id, e := range submap {
+			m := siteEntries.Mutable(protoreflect.ValueOfString(id).MapKey()).Message()
+
+			set := func(name protoreflect.Name, v protoreflect.Value) {
+				m.Set(protoField(m, name), v)
+			}
+
+			set("name", protoreflect.ValueOfString(e.Name))
+			set("value", protoreflect.ValueOfString(e.Value))
+			set("quoted", protoreflect.ValueOfBool(e.Quoted))
+			set("domain", protoreflect.ValueOfString(e.Domain))
+			set("path", protoreflect.ValueOfString(e.Path))
+			set("same_site", protoreflect.ValueOfString(e.SameSite))
+			set("secure", protoreflect.ValueOfBool(e.Secure))
+			set("http_only", protoreflect.ValueOfBool(e.HttpOnly))
+			set("persistent", protoreflect.ValueOfBool(e.Persistent))
+			set("host_only", protoreflect.ValueOfBool(e.HostOnly))
+			set("partitioned", protoreflect.ValueOfBool(e.Partitioned))
+			set("partition_key", protoreflect.ValueOfString(e.PartitionKey))
+			set("seq_num", protoreflect.ValueOfUint64(e.SeqNum))
+
+			timeToProto(m, "expires", e.Expires)
+			timeToProto(m, "creation", e.Creation)
+			timeToProto(m, "last_access", e.LastAccess)
+		}
+	}
+
+	return msg
+}
+
+func protoField(m protoreflect.Message, name protoreflect.Name) protoreflect.FieldDescriptor {
+	return m.Descriptor().Fields().ByName(name)
+}
+
+// timeFromProto reads the google.protobuf.Timestamp field name of m. A missing field is the zero time.
+func timeFromProto(m protoreflect.Message, name protoreflect.Name) time.Time {
+	fd := protoField(m, name)
+	if !m.Has(fd) {
+		return time.Time{}
+	}
+
+	ts := m.Get(fd).Message()
+
+	return time.Unix(ts.Get(protoField(ts, "seconds")).Int(), ts.Get(protoField(ts, "nanos")).Int()).UTC()
+}
+
+// timeToProto writes t to the google.protobuf.Timestamp field name of m, unless t is zero.
+func timeToProto(m protoreflect.Message, name protoreflect.Name, t time.Time) {
+	if t.IsZero() {
+		return
+	}
+
+	ts := m.Mutable(protoField(m, name)).Message()
+
+	ts.Set(protoField(ts, "seconds"), protoreflect.ValueOfInt64(t.Unix()))
+	ts.Set(protoField(ts, "nanos"), protoreflect.ValueOfInt32(int32(t.Nanosecond()))) //nolint: gosec
+}
@@ -0,0 +1,318 @@
+package cookiejar
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	binaryCookiesMagic        = "cook"
+	binaryCookiesPageHeader   = 0x00000100
+	binaryCookiesFooter       = 0x071720050000004b
+	binaryCookiesRecordHeader = 56
+
+	binaryCookiesFlagSecure   = 1
+	binaryCookiesFlagHTTPOnly = 4
+)
+
+// macEpoch is the reference date of the Mac absolute time.
+var macEpoch = time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+
+var errMalformedBinaryCookies = errors.New("cookiejar: malformed binarycookies file")
+
+var _ EntrySerDer = (*binaryCookiesSerDer)(nil)
+
+// binaryCookiesSerDer is a serializer and deserializer for the Cookies.binarycookies format of Safari and iOS.
+//
+// The file starts with the "cook" magic, the number of pages and the size of each page, in big-endian. Each page has a
+// header, the number of cookies and their offsets, in little-endian, followed by the cookie records. A record has the
+// flags, the offsets of the domain, name, path and value strings and the expiry and creation as Mac absolute time. The
+// file ends with a checksum of the pages, a footer and an optional binary property list, which is ignored.
+type binaryCookiesSerDer struct{}
+
+// NewBinaryCookiesSerDer returns a serializer/deserializer for the Cookies.binarycookies format of Safari and iOS.
+//
+// The format does not have the eTLD+1 keys, see [EntrySerDer], nor SameSite and last access time. Session cookies are
+// written with an expiry of 0.
+func NewBinaryCookiesSerDer() EntrySerDer {
+	return binaryCookiesSerDer{}
+}
+
+func (binaryCookiesSerDer) Serialize(w io.Writer, entries map[string]map[string]Entry) error {
+	keys := make([]string, 0, len(entries))
+
+	for key := range entries {
+		keys = append(keys, key)
+	}
+
+	slices.Sort(keys)
+
+	pages := make([][]byte, 0, len(keys))
+
+	for _, key := range keys {
+		pages = append(pages, encodeBinaryCookiesPage(sortedEntries(map[string]map[string]Entry{key: entries[key]})))
+	}
+
+	var buf bytes.Buffer
+
+	buf.WriteString(binaryCookiesMagic)
+	_ = binary.Write(&buf, binary.BigEndian, uint32(len(pages))) //nolint: errcheck,gosec
+
+	for _, page := range pages {
+		_ = binary.Write(&buf, binary.BigEndian, uint32(len(page))) //nolint: errcheck,gosec
+	}
+
+	var checksum uint32
+
+	for _, page := range pages {
+		buf.Write(page)
+
+		checksum += binaryCookiesChecksum(page)
+	}
+
+	_ = binary.Write(&buf, binary.BigEndian, checksum)                    //nolint: errcheck
+	_ = binary.Write(&buf, binary.BigEndian, uint64(binaryCookiesFooter)) //nolint: errcheck
+
+	_, err := w.Write(buf.Bytes())
+
+	return err
+}
+
+func (binaryCookiesSerDer) Deserialize(r io.Reader) (map[string]map[string]Entry, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < 8 || string(data[:4]) != binaryCookiesMagic {
+		return nil, fmt.Errorf("%w: missing magic", errMalformedBinaryCookies)
+	}
+
+	numPages := int(binary.BigEndian.Uint32(data[4:8]))
+	offset := 8 + 4*numPages
+
+	if numPages < 0 || offset > len(data) {
+		return nil, fmt.Errorf("%w: truncated page sizes", errMalformedBinaryCookies)
+	}
+
+	entries := make(map[string]map[string]Entry)
+	seqNum := uint64(0)
+
+	var checksum uint32
+
+	for i := range numPages {
+		size := int(binary.BigEndian.Uint32(data[8+4*i:]))
+		if size > len(data)-offset {
+			return nil, fmt.Errorf("%w: truncated page %d", errMalformedBinaryCookies, i)
+		}
+
+		page := data[offset : offset+size]
+		offset += size
+		checksum += binaryCookiesChecksum(page)
+
+		pageEntries, err := decodeBinaryCookiesPage(page)
+		if err != nil {
+			return nil, fmt.Errorf("%w: page %d: %w", errMalformedBinaryCookies, i, err)
+		}
+
+		for _, e := range pageEntries {
+			e.SeqNum = seqNum
+			seqNum++
+
+			addEntry(entries, e)
+		}
+	}
+
+	if len(data) >= offset+4 && binary.BigEndian.Uint32(data[offset:]) != checksum {
+		return nil, fmt.Errorf("%w: checksum mismatch", errMalformedBinaryCookies)
+	}
+
+	return entries, nil
+}
+
+func encodeBinaryCookiesPage(entries []Entry) []byte {
+	records := make([][]byte, len(entries))
+
+	for i, e := range entries {
+		records[i] = encodeBinaryCookiesRecord(e)
+	}
+
+	var buf bytes.Buffer
+
+	_ = binary.Write(&buf, binary.BigEndian, uint32(binaryCookiesPageHeader)) //nolint: errcheck
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(len(records)))        //nolint: errcheck,gosec
+
+	offset := 4 + 4 + 4*len(records) + 4
+
+	for _, record := range records {
+		_ = binary.Write(&buf, binary.LittleEndian, uint32(offset)) //nolint: errcheck,gosec
+
+		offset += len(record)
+	}
+
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(0)) //nolint: errcheck
+
+	for _, record := range records {
+		buf.Write(record)
+	}
+
+	return buf.Bytes()
+}
+
+func encodeBinaryCookiesRecord(e Entry) []byte {
+	domain := e.Domain
+	if !e.HostOnly {
+		domain = "." + domain
+	}
+
+	var flags uint32
+
+	if e.Secure {
+		flags |= binaryCookiesFlagSecure
+	}
+
+	if e.HttpOnly {
+		flags |= binaryCookiesFlagHTTPOnly
+	}
+
+	var (
+		strs    bytes.Buffer
+		offsets [4]uint32
+	)
+
+	for i, s := range []string{domain, e.Name, e.Path, e.Value} {
+		offsets[i] = uint32(binaryCookiesRecordHeader + strs.Len()) //nolint: gosec
+
+		strs.WriteString(s)
+		strs.WriteByte(0)
+	}
+
+	var expires float64
+	if e.Persistent {
+		expires = toMacTime(e.Expires)
+	}
+
+	var buf bytes.Buffer
+
+	_ = binary.Write(&buf, binary.LittleEndian, []uint32{ //nolint: errcheck
+		uint32(binaryCookiesRecordHeader + strs.Len()), //nolint: gosec
+		1, flags, 0,
+		offsets[0], offsets[1], offsets[2], offsets[3],
+		0, 0,
+	})
+	_ = binary.Write(&buf, binary.LittleEndian, []float64{expires, toMacTime(e.Creation)}) //nolint: errcheck
+
+	buf.Write(strs.Bytes())
+
+	return buf.Bytes()
+}
+
+func decodeBinaryCookiesPage(page []byte) ([]Entry, error) {
+	if len(page) < 8 || binary.BigEndian.Uint32(page) != binaryCookiesPageHeader {
+		return nil, errors.New("invalid page header")
+	}
+
+	numCookies := int(binary.LittleEndian.Uint32(page[4:]))
+	if numCookies < 0 || 8+4*numCookies > len(page) {
+		return nil, errors.New("truncated cookie offsets")
+	}
+
+	entries := make([]Entry, 0, numCookies)
+
+	for i := range numCookies {
+		offset := int(binary.LittleEndian.Uint32(page[8+4*i:]))
+		if offset+4 > len(page) {
+			return nil, fmt.Errorf("cookie %d: out of page", i)
+		}
+
+		size := int(binary.LittleEndian.Uint32(page[offset:]))
+		if size < binaryCookiesRecordHeader || size > len(page)-offset {
+			return nil, fmt.Errorf("cookie %d: invalid size", i)
+		}
+
+		e, err := decodeBinaryCookiesRecord(page[offset : offset+size])
+		if err != nil {
+			return nil, fmt.Errorf("cookie %d: %w", i, err)
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
+
+func decodeBinaryCookiesRecord(record []byte) (Entry, error) {
+	flags := binary.LittleEndian.Uint32(record[8:])
+
+	var strs [4]string
+
+	for i := range strs {
+		offset := int(binary.LittleEndian.Uint32(record[16+4*i:]))
+		if offset >= len(record) {
+			return Entry{}, errors.New("string out of record")
+		}
+
+		end := bytes.IndexByte(record[offset:], 0)
+		if end < 0 {
+			return Entry{}, errors.New("unterminated string")
+		}
+
+		strs[i] = string(record[offset : offset+end])
+	}
+
+	expires := math.Float64frombits(binary.LittleEndian.Uint64(record[40:]))
+	creation := math.Float64frombits(binary.LittleEndian.Uint64(record[48:]))
+
+	e := Entry{
+		Name:     strs[1],
+		Value:    strs[3],
+		Domain:   strings.ToLower(strings.TrimPrefix(strs[0], ".")),
+		Path:     strs[2],
+		Secure:   flags&binaryCookiesFlagSecure != 0,
+		HttpOnly: flags&binaryCookiesFlagHTTPOnly != 0,
+		HostOnly: !strings.HasPrefix(strs[0], "."),
+		Expires:  endOfTime,
+		Creation: fromMacTime(creation),
+	}
+
+	if expires != 0 {
+		e.Expires = fromMacTime(expires)
+		e.Persistent = true
+	}
+
+	e.LastAccess = e.Creation
+
+	return e, nil
+}
+
+// binaryCookiesChecksum sums every fourth byte of a page.
+func binaryCookiesChecksum(page []byte) uint32 {
+	var sum uint32
+
+	for i := 0; i < len(page); i += 4 {
+		sum += uint32(page[i])
+	}
+
+	return sum
+}
+
+func toMacTime(t time.Time) float64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return float64(t.Unix()-macEpoch.Unix()) + float64(t.Nanosecond())/float64(time.Second)
+}
+
+func fromMacTime(t float64) time.Time {
+	sec, frac := math.Modf(t)
+
+	return time.Unix(macEpoch.Unix()+int64(sec), int64(math.Round(frac*float64(time.Second)))).UTC()
+}
@@ -0,0 +1,175 @@
+package cookiejar_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.nhat.io/cookiejar"
+)
+
+func TestBinaryCookiesSerDer_Deserialize(t *testing.T) {
+	t.Parallel()
+
+	f, err := os.Open("testdata/Cookies.binarycookies")
+	require.NoError(t, err)
+
+	defer f.Close() //nolint: errcheck
+
+	actual, err := cookiejar.NewBinaryCookiesSerDer().Deserialize(f)
+	require.NoError(t, err)
+
+	expires := time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+	expected := map[string]map[string]cookiejar.Entry{
+		"example.com": {
+			"www.example.com;/;session": {
+				Name:       "session",
+				Value:      "abc",
+				Domain:     "www.example.com",
+				Path:       "/",
+				Secure:     true,
+				HttpOnly:   true,
+				Persistent: true,
+				HostOnly:   true,
+				Expires:    expires,
+				Creation:   time.Unix(1700000000, 500000000).UTC(),
+				LastAccess: time.Unix(1700000000, 500000000).UTC(),
+				SeqNum:     0,
+			},
+			"example.com;/app;theme": {
+				Name:       "theme",
+				Value:      "dark",
+				Domain:     "example.com",
+				Path:       "/app",
+				Persistent: true,
+				Expires:    expires,
+				Creation:   time.Unix(1700000002, 0).UTC(),
+				LastAccess: time.Unix(1700000002, 0).UTC(),
+				SeqNum:     1,
+			},
+		},
+		"example.org": {
+			"example.org;/;id": {
+				Name:       "id",
+				Value:      "42",
+				Domain:     "example.org",
+				Path:       "/",
+				Secure:     true,
+				HostOnly:   true,
+				Expires:    time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC),
+				Creation:   time.Unix(1700000004, 0).UTC(),
+				LastAccess: time.Unix(1700000004, 0).UTC(),
+				SeqNum:     2,
+			},
+		},
+	}
+
+	assert.Equal(t, expected, actual)
+}
+
+func TestBinaryCookiesSerDer_Deserialize_Error(t *testing.T) {
+	t.Parallel()
+
+	fixture, err := os.ReadFile("testdata/Cookies.binarycookies")
+	require.NoError(t, err)
+
+	badChecksum := bytes.Clone(fixture)
+	badChecksum[bytes.Index(fixture, []byte{0x07, 0x17, 0x20, 0x05, 0x00, 0x00, 0x00, 0x4b})-1] ^= 0xff
+
+	testCases := []struct {
+		scenario      string
+		data          []byte
+		expectedError string
+	}{
+		{
+			scenario:      "missing magic",
+			data:          []byte("{}"),
+			expectedError: "cookiejar: malformed binarycookies file: missing magic",
+		},
+		{
+			scenario:      "truncated page",
+			data:          fixture[:100],
+			expectedError: "cookiejar: malformed binarycookies file: truncated page 0",
+		},
+		{
+			scenario:      "checksum mismatch",
+			data:          badChecksum,
+			expectedError: "cookiejar: malformed binarycookies file: checksum mismatch",
+		},
+		{
+			scenario:      "invalid page header",
+			data:          append([]byte("cook\x00\x00\x00\x01\x00\x00\x00\x08"), 0, 0, 0, 0, 0, 0, 0, 0),
+			expectedError: "cookiejar: malformed binarycookies file: page 0: invalid page header",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := cookiejar.NewBinaryCookiesSerDer().Deserialize(bytes.NewReader(tc.data))
+
+			assert.Nil(t, actual)
+			assert.EqualError(t, err, tc.expectedError)
+		})
+	}
+}
+
+func TestBinaryCookiesSerDer_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	s := cookiejar.NewBinaryCookiesSerDer()
+
+	fixture, err := os.ReadFile("testdata/Cookies.binarycookies")
+	require.NoError(t, err)
+
+	expected, err := s.Deserialize(bytes.NewReader(fixture))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+
+	err = s.Serialize(&buf, expected)
+	require.NoError(t, err)
+
+	// The fixture has a property list after the footer, which is not written.
+	assert.Equal(t, fixture[:buf.Len()], buf.Bytes())
+
+	actual, err := s.Deserialize(&buf)
+	require.NoError(t, err)
+
+	assert.Equal(t, expected, actual)
+}
+
+func TestPersistentJar_BinaryCookiesSerDer(t *testing.T) {
+	t.Parallel()
+
+	const filePath = "/tmp/Cookies.binarycookies"
+
+	fixture, err := os.ReadFile("testdata/Cookies.binarycookies")
+	require.NoError(t, err)
+
+	fs := afero.NewMemMapFs()
+
+	require.NoError(t, afero.WriteFile(fs, filePath, fixture, 0o600))
+
+	j := cookiejar.NewPersistentJar(
+		cookiejar.WithFs(fs),
+		cookiejar.WithFilePath(filePath),
+		cookiejar.WithSerDer(cookiejar.NewBinaryCookiesSerDer()),
+	)
+
+	actual := j.Cookies(&url.URL{Scheme: "https", Host: "www.example.com", Path: "/app/"})
+	expected := []*http.Cookie{
+		{Name: "theme", Value: "dark"},
+		{Name: "session", Value: "abc"},
+	}
+
+	assert.Equal(t, expected, actual)
+}
@@ -0,0 +1,221 @@
+package boltstore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	bolterrors "go.etcd.io/bbolt/errors"
+
+	"go.nhat.io/cookiejar"
+)
+
+var _ cookiejar.Store = (*Store)(nil)
+
+// Store is a cookiejar.Store in a bbolt database.
+//
+// Every eTLD+1 is a bucket and every entry is a JSON value keyed by its domain;path;name id in the bucket. An update
+// is one read-write transaction that only puts and deletes the entries that changed. The buckets are read lazily, the
+// first time the cookies of their site are requested, and are then cached, as bbolt does not let another process open
+// the database.
+type Store struct {
+	db *bolt.DB
+
+	mu     sync.Mutex
+	loaded map[string]map[string]cookiejar.Entry
+}
+
+// Open opens or creates the bbolt database at path. It waits at most timeout for the lock of the database, a zero
+// timeout waits indefinitely.
+func Open(path string, mode os.FileMode, timeout time.Duration) (*Store, error) {
+	db, err := bolt.Open(path, mode, &bolt.Options{Timeout: timeout})
+	if err != nil {
+		return nil, fmt.Errorf("could not open cookies database: %w", err)
+	}
+
+	return New(db), nil
+}
+
+// New returns a store in an opened bbolt database. The database must not be modified while the store is used, as
+// the store caches the buckets it reads.
+func New(db *bolt.DB) *Store {
+	return &Store{
+		db:     db,
+		loaded: make(map[string]map[string]cookiejar.Entry),
+	}
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Entries returns the entries of key, reading its bucket if it has not been read yet.
+func (s *Store) Entries(key string) (map[string]cookiejar.Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.load(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return maps.Clone(entries), nil
+}
+
+// All returns the entries of every bucket.
+func (s *Store) All() (map[string]map[string]cookiejar.Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+			key := string(name)
+
+			if _, ok := s.loaded[key]; ok {
+				return nil
+			}
+
+			entries, err := readBucket(b)
+			if err != nil {
+				return fmt.Errorf("could not read cookies of %q: %w", key, err)
+			}
+
+			s.loaded[key] = entries
+
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	all := make(map[string]map[string]cookiejar.Entry, len(s.loaded))
+
+	for key, entries := range s.loaded {
+		if len(entries) > 0 {
+			all[key] = maps.Clone(entries)
+		}
+	}
+
+	return all, nil
+}
+
+// Update calls fn with the entries of key and writes the entries that changed in one transaction.
+func (s *Store) Update(key string, fn func(entries map[string]cookiejar.Entry) bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	before, err := s.load(key)
+	if err != nil {
+		return err
+	}
+
+	after := maps.Clone(before)
+	if after == nil {
+		after = make(map[string]cookiejar.Entry)
+	}
+
+	if !fn(after) {
+		return nil
+	}
+
+	upserts, deletes := cookiejar.DiffEntries(before, after)
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		if len(after) == 0 {
+			if err := tx.DeleteBucket([]byte(key)); err != nil && !errors.Is(err, bolterrors.ErrBucketNotFound) {
+				return err
+			}
+
+			return nil
+		}
+
+		b, err := tx.CreateBucketIfNotExists([]byte(key))
+		if err != nil {
+			return err
+		}
+
+		for _, e := range deletes {
+			if err := b.Delete([]byte(e.ID())); err != nil {
+				return err
+			}
+		}
+
+		for _, e := range upserts {
+			data, err := json.Marshal(e)
+			if err != nil {
+				return err
+			}
+
+			if err := b.Put([]byte(e.ID()), data); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("could not update cookies of %q: %w", key, err)
+	}
+
+	s.loaded[key] = after
+
+	return nil
+}
+
+// load returns the cached entries of key, reading its bucket if needed. The caller must hold s.mu and must not modify
+// the result.
+func (s *Store) load(key string) (map[string]cookiejar.Entry, error) {
+	if entries, ok := s.loaded[key]; ok {
+		return entries, nil
+	}
+
+	var entries map[string]cookiejar.Entry
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(key))
+		if b == nil {
+			return nil
+		}
+
+		var err error
+
+		entries, err = readBucket(b)
+
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not read cookies of %q: %w", key, err)
+	}
+
+	s.loaded[key] = entries
+
+	return entries, nil
+}
+
+func readBucket(b *bolt.Bucket) (map[string]cookiejar.Entry, error) {
+	entries := make(map[string]cookiejar.Entry)
+
+	err := b.ForEach(func(k, v []byte) error {
+		var e cookiejar.Entry
+
+		if err := json.Unmarshal(v, &e); err != nil {
+			return fmt.Errorf("could not decode cookie %q: %w", k, err)
+		}
+
+		entries[string(k)] = e
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
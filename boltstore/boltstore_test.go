@@ -0,0 +1,130 @@
+package boltstore_test
+
+import (
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+
+	"go.nhat.io/cookiejar"
+	"go.nhat.io/cookiejar/boltstore"
+)
+
+func TestStore(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "cookies.db")
+	exampleCom := &url.URL{Scheme: "https", Host: "www.example.com", Path: "/"}
+	exampleOrg := &url.URL{Scheme: "https", Host: "example.org", Path: "/"}
+
+	store, err := boltstore.Open(path, 0o600, 0)
+	require.NoError(t, err)
+
+	jar, err := cookiejar.New(&cookiejar.Options{Store: store})
+	require.NoError(t, err)
+
+	jar.SetCookies(exampleCom, []*http.Cookie{
+		{Name: "id", Value: "42"},
+		{Name: "theme", Value: "dark", Domain: "example.com", MaxAge: 3600},
+	})
+	jar.SetCookies(exampleOrg, []*http.Cookie{{Name: "lang", Value: "en"}})
+	jar.SetCookies(exampleOrg, []*http.Cookie{{Name: "lang", MaxAge: -1}})
+
+	entries := jar.Entries()
+
+	require.NoError(t, store.Close())
+
+	// Every site is a bucket, keyed by the ids of the entries.
+	db, err := bolt.Open(path, 0o600, nil)
+	require.NoError(t, err)
+
+	buckets := map[string][]string{}
+
+	err = db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+			return b.ForEach(func(k, _ []byte) error {
+				buckets[string(name)] = append(buckets[string(name)], string(k))
+
+				return nil
+			})
+		})
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	assert.Equal(t, map[string][]string{"example.com": {"example.com;/;theme", "www.example.com;/;id"}}, buckets)
+
+	store, err = boltstore.Open(path, 0o600, 0)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		assert.NoError(t, store.Close())
+	})
+
+	reopened, err := cookiejar.New(&cookiejar.Options{Store: store})
+	require.NoError(t, err)
+
+	expected := []*http.Cookie{
+		{Name: "id", Value: "42"},
+		{Name: "theme", Value: "dark"},
+	}
+
+	assert.Equal(t, expected, reopened.Cookies(exampleCom))
+	assert.Empty(t, reopened.Cookies(exampleOrg))
+
+	actual := reopened.Entries()
+
+	require.Len(t, actual, 2)
+
+	for i := range actual {
+		assert.True(t, entries[i].Expires.Equal(actual[i].Expires))
+		assert.Equal(t, entries[i].SeqNum, actual[i].SeqNum)
+		assert.Equal(t, entries[i].Name, actual[i].Name)
+	}
+}
+
+func TestStore_Lazy(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "cookies.db")
+	u := &url.URL{Scheme: "https", Host: "example.com", Path: "/"}
+
+	store, err := boltstore.Open(path, 0o600, 0)
+	require.NoError(t, err)
+
+	jar, err := cookiejar.New(&cookiejar.Options{Store: store})
+	require.NoError(t, err)
+
+	jar.SetCookies(u, []*http.Cookie{{Name: "id", Value: "42"}})
+	require.NoError(t, store.Close())
+
+	db, err := bolt.Open(path, 0o600, nil)
+	require.NoError(t, err)
+
+	store = boltstore.New(db)
+
+	t.Cleanup(func() {
+		assert.NoError(t, store.Close())
+	})
+
+	// The bucket is not read before the site is requested, a change made under the store is seen on first request.
+	err = db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("example.com")).Put([]byte("example.com;/;lang"),
+			[]byte(`{"Name":"lang","Value":"en","Domain":"example.com","Path":"/","HostOnly":true,"SeqNum":1}`))
+	})
+	require.NoError(t, err)
+
+	jar, err = cookiejar.New(&cookiejar.Options{Store: store})
+	require.NoError(t, err)
+
+	expected := []*http.Cookie{
+		{Name: "lang", Value: "en"},
+		{Name: "id", Value: "42"},
+	}
+
+	assert.Equal(t, expected, jar.Cookies(u))
+}
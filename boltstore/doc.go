@@ -0,0 +1,2 @@
+// Package boltstore stores the entries of a cookiejar.Jar in a bbolt database.
+package boltstore
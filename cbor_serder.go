@@ -0,0 +1,48 @@
+package cookiejar
+
+import (
+	"io"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+var _ EntrySerDer = (*cborSerDer)(nil)
+
+// cborSerDer is a serializer and deserializer for CBOR (RFC 8949).
+type cborSerDer struct {
+	enc cbor.EncMode
+	dec cbor.DecMode
+}
+
+// NewCBORSerDer returns a serializer/deserializer for CBOR (RFC 8949). The maps are sorted and the times are encoded as
+// RFC 3339 strings with nanoseconds, so the output is deterministic.
+func NewCBORSerDer() EntrySerDer {
+	enc, err := cbor.EncOptions{
+		Sort: cbor.SortCanonical,
+		Time: cbor.TimeRFC3339Nano,
+	}.EncMode()
+	if err != nil {
+		panic(err)
+	}
+
+	dec, err := cbor.DecOptions{}.DecMode()
+	if err != nil {
+		panic(err)
+	}
+
+	return cborSerDer{enc: enc, dec: dec}
+}
+
+func (s cborSerDer) Serialize(w io.Writer, entries map[string]map[string]Entry) error {
+	return s.enc.NewEncoder(w).Encode(entries)
+}
+
+func (s cborSerDer) Deserialize(r io.Reader) (map[string]map[string]Entry, error) {
+	var entries map[string]map[string]Entry
+
+	if err := s.dec.NewDecoder(r).Decode(&entries); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
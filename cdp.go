@@ -0,0 +1,178 @@
+package cookiejar
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+var _ EntrySerDer = (*cdpSerDer)(nil)
+
+// CDPCookie is a Network.Cookie of the Chrome DevTools Protocol, as returned by Network.getAllCookies, Puppeteer and
+// chromedp.
+type CDPCookie struct {
+	Name               string                 `json:"name"`
+	Value              string                 `json:"value"`
+	Domain             string                 `json:"domain"`
+	Path               string                 `json:"path"`
+	Expires            float64                `json:"expires"`
+	Size               int                    `json:"size"`
+	HTTPOnly           bool                   `json:"httpOnly"`
+	Secure             bool                   `json:"secure"`
+	Session            bool                   `json:"session"`
+	SameSite           string                 `json:"sameSite,omitempty"`
+	Priority           string                 `json:"priority"`
+	SameParty          bool                   `json:"sameParty"`
+	SourceScheme       string                 `json:"sourceScheme"`
+	SourcePort         int                    `json:"sourcePort"`
+	PartitionKey       *CDPCookiePartitionKey `json:"partitionKey,omitempty"`
+	PartitionKeyOpaque bool                   `json:"partitionKeyOpaque,omitempty"`
+}
+
+// CDPCookiePartitionKey is a Network.CookiePartitionKey of the Chrome DevTools Protocol.
+type CDPCookiePartitionKey struct {
+	TopLevelSite         string `json:"topLevelSite"`
+	HasCrossSiteAncestor bool   `json:"hasCrossSiteAncestor"`
+}
+
+// UnmarshalJSON unmarshals the partition key from an object or, as in older versions of the protocol, from the
+// top-level site string.
+func (k *CDPCookiePartitionKey) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		return json.Unmarshal(data, &k.TopLevelSite)
+	}
+
+	type partitionKey CDPCookiePartitionKey
+
+	return json.Unmarshal(data, (*partitionKey)(k))
+}
+
+// EntriesFromCDP converts the cookies of the Chrome DevTools Protocol to entries. The priority, same party, source
+// scheme and source port are not kept.
+func EntriesFromCDP(cookies []CDPCookie) []Entry {
+	entries := make([]Entry, len(cookies))
+
+	for i, c := range cookies {
+		e := Entry{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   strings.ToLower(strings.TrimPrefix(c.Domain, ".")),
+			Path:     c.Path,
+			SameSite: sameSiteAttribute(c.SameSite),
+			Secure:   c.Secure,
+			HttpOnly: c.HTTPOnly,
+			HostOnly: !strings.HasPrefix(c.Domain, "."),
+			Expires:  endOfTime,
+			SeqNum:   uint64(i), //nolint: gosec
+		}
+
+		if c.PartitionKey != nil {
+			e.Partitioned = true
+			e.PartitionKey = c.PartitionKey.TopLevelSite
+		}
+
+		if !c.Session && c.Expires >= 0 {
+			sec, frac := math.Modf(c.Expires)
+
+			e.Expires = time.Unix(int64(sec), int64(math.Round(frac*float64(time.Second)))).UTC()
+			e.Persistent = true
+		}
+
+		entries[i] = e
+	}
+
+	return entries
+}
+
+// EntriesToCDP converts entries to cookies of the Chrome DevTools Protocol. The entries do not have priority and source,
+// so the cookies get the defaults of Chrome: the "Medium" priority, the "Unset" source scheme and the -1 source port.
+func EntriesToCDP(entries []Entry) []CDPCookie {
+	cookies := make([]CDPCookie, len(entries))
+
+	for i, e := range entries {
+		c := CDPCookie{
+			Name:         e.Name,
+			Value:        e.Value,
+			Domain:       e.Domain,
+			Path:         e.Path,
+			Expires:      -1,
+			Size:         len(e.Name) + len(e.Value),
+			HTTPOnly:     e.HttpOnly,
+			Secure:       e.Secure,
+			Session:      !e.Persistent,
+			Priority:     "Medium",
+			SourceScheme: "Unset",
+			SourcePort:   -1,
+		}
+
+		if !e.HostOnly {
+			c.Domain = "." + c.Domain
+		}
+
+		if e.SameSite != "" {
+			c.SameSite = sameSiteName(e.SameSite)
+		}
+
+		if e.Persistent {
+			c.Expires = float64(e.Expires.Unix()) + float64(e.Expires.Nanosecond())/float64(time.Second)
+		}
+
+		if e.Partitioned {
+			c.PartitionKey = &CDPCookiePartitionKey{TopLevelSite: e.PartitionKey}
+		}
+
+		cookies[i] = c
+	}
+
+	return cookies
+}
+
+// cdpSerDer is a serializer and deserializer for a JSON array of cookies of the Chrome DevTools Protocol.
+type cdpSerDer struct{}
+
+// NewCDPSerDer returns a serializer/deserializer for a JSON array of cookies of the Chrome DevTools Protocol. The
+// result of Network.getAllCookies, an object with a "cookies" array, is also accepted when deserializing.
+//
+// The format does not store the eTLD+1 keys, see [EntrySerDer].
+func NewCDPSerDer() EntrySerDer {
+	return cdpSerDer{}
+}
+
+func (cdpSerDer) Serialize(w io.Writer, entries map[string]map[string]Entry) error {
+	return json.NewEncoder(w).Encode(EntriesToCDP(sortedEntries(entries)))
+}
+
+func (cdpSerDer) Deserialize(r io.Reader) (map[string]map[string]Entry, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var cookies []CDPCookie
+
+	if data = bytes.TrimSpace(data); len(data) > 0 && data[0] == '{' {
+		var result struct {
+			Cookies []CDPCookie `json:"cookies"`
+		}
+
+		err = json.Unmarshal(data, &result)
+		cookies = result.Cookies
+	} else {
+		err = json.Unmarshal(data, &cookies)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make(map[string]map[string]Entry)
+
+	for _, e := range EntriesFromCDP(cookies) {
+		addEntry(entries, e)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,218 @@
+package cookiejar_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swaggest/assertjson"
+
+	"go.nhat.io/cookiejar"
+)
+
+const cdpCookiesJSON = `[
+  {
+    "name": "session",
+    "value": "abc",
+    "domain": "www.example.com",
+    "path": "/",
+    "expires": -1,
+    "size": 10,
+    "httpOnly": true,
+    "secure": true,
+    "session": true,
+    "sameSite": "Strict",
+    "priority": "High",
+    "sameParty": false,
+    "sourceScheme": "Secure",
+    "sourcePort": 443
+  },
+  {
+    "name": "theme",
+    "value": "dark",
+    "domain": ".example.com",
+    "path": "/app",
+    "expires": 4102444800.5,
+    "size": 9,
+    "httpOnly": false,
+    "secure": false,
+    "session": false,
+    "priority": "Medium",
+    "sameParty": false,
+    "sourceScheme": "NonSecure",
+    "sourcePort": 80
+  },
+  {
+    "name": "embed",
+    "value": "1",
+    "domain": "widget.example.net",
+    "path": "/",
+    "expires": 4102444800,
+    "size": 6,
+    "httpOnly": false,
+    "secure": true,
+    "session": false,
+    "sameSite": "None",
+    "priority": "Low",
+    "sameParty": false,
+    "sourceScheme": "Secure",
+    "sourcePort": 443,
+    "partitionKey": "https://example.org"
+  }
+]`
+
+func TestEntriesFromCDP(t *testing.T) {
+	t.Parallel()
+
+	actual, err := cookiejar.NewCDPSerDer().Deserialize(strings.NewReader(cdpCookiesJSON))
+	require.NoError(t, err)
+
+	expected := map[string]map[string]cookiejar.Entry{
+		"example.com": {
+			"www.example.com;/;session": {
+				Name:     "session",
+				Value:    "abc",
+				Domain:   "www.example.com",
+				Path:     "/",
+				SameSite: "SameSite=Strict",
+				Secure:   true,
+				HttpOnly: true,
+				HostOnly: true,
+				Expires:  time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC),
+				SeqNum:   0,
+			},
+			"example.com;/app;theme": {
+				Name:       "theme",
+				Value:      "dark",
+				Domain:     "example.com",
+				Path:       "/app",
+				Persistent: true,
+				Expires:    time.Date(2100, 1, 1, 0, 0, 0, 500000000, time.UTC),
+				SeqNum:     1,
+			},
+		},
+		"example.net": {
+			"widget.example.net;/;embed": {
+				Name:         "embed",
+				Value:        "1",
+				Domain:       "widget.example.net",
+				Path:         "/",
+				Secure:       true,
+				Persistent:   true,
+				HostOnly:     true,
+				Partitioned:  true,
+				PartitionKey: "https://example.org",
+				Expires:      time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC),
+				SeqNum:       2,
+			},
+		},
+	}
+
+	assert.Equal(t, expected, actual)
+}
+
+func TestCDPSerDer_Deserialize_GetAllCookiesResult(t *testing.T) {
+	t.Parallel()
+
+	const result = `{"cookies": [{"name": "id", "value": "42", "domain": "example.com", "path": "/", "expires": -1, "session": true,
+		"partitionKey": {"topLevelSite": "https://example.org", "hasCrossSiteAncestor": true}}]}`
+
+	actual, err := cookiejar.NewCDPSerDer().Deserialize(strings.NewReader(result))
+	require.NoError(t, err)
+
+	expected := map[string]map[string]cookiejar.Entry{
+		"example.com": {
+			"example.com;/;id": {
+				Name:         "id",
+				Value:        "42",
+				Domain:       "example.com",
+				Path:         "/",
+				HostOnly:     true,
+				Partitioned:  true,
+				PartitionKey: "https://example.org",
+				Expires:      time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC),
+			},
+		},
+	}
+
+	assert.Equal(t, expected, actual)
+}
+
+func TestCDPSerDer_Deserialize_Error(t *testing.T) {
+	t.Parallel()
+
+	actual, err := cookiejar.NewCDPSerDer().Deserialize(strings.NewReader(`[{"name": 42}]`))
+
+	assert.Nil(t, actual)
+	assert.ErrorContains(t, err, "cannot unmarshal number")
+}
+
+func TestCDPSerDer_Serialize(t *testing.T) {
+	t.Parallel()
+
+	s := cookiejar.NewCDPSerDer()
+
+	entries, err := s.Deserialize(strings.NewReader(cdpCookiesJSON))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+
+	err = s.Serialize(&buf, entries)
+	require.NoError(t, err)
+
+	// Priority and source are not kept.
+	expected := `[
+  {
+    "name": "session",
+    "value": "abc",
+    "domain": "www.example.com",
+    "path": "/",
+    "expires": -1,
+    "size": 10,
+    "httpOnly": true,
+    "secure": true,
+    "session": true,
+    "sameSite": "Strict",
+    "priority": "Medium",
+    "sameParty": false,
+    "sourceScheme": "Unset",
+    "sourcePort": -1
+  },
+  {
+    "name": "theme",
+    "value": "dark",
+    "domain": ".example.com",
+    "path": "/app",
+    "expires": 4102444800.5,
+    "size": 9,
+    "httpOnly": false,
+    "secure": false,
+    "session": false,
+    "priority": "Medium",
+    "sameParty": false,
+    "sourceScheme": "Unset",
+    "sourcePort": -1
+  },
+  {
+    "name": "embed",
+    "value": "1",
+    "domain": "widget.example.net",
+    "path": "/",
+    "expires": 4102444800,
+    "size": 6,
+    "httpOnly": false,
+    "secure": true,
+    "session": false,
+    "priority": "Medium",
+    "sameParty": false,
+    "sourceScheme": "Unset",
+    "sourcePort": -1,
+    "partitionKey": {"topLevelSite": "https://example.org", "hasCrossSiteAncestor": false}
+  }
+]`
+
+	assertjson.Equal(t, []byte(expected), buf.Bytes())
+}
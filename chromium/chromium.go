@@ -0,0 +1,319 @@
+package chromium
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.nhat.io/cookiejar"
+
+	_ "modernc.org/sqlite" // Pure-Go SQLite driver.
+)
+
+// Values of the samesite column of cookies.
+const (
+	sameSiteLax    = 1
+	sameSiteStrict = 2
+)
+
+// columns are the columns of the cookies table that are read, in the order of the scan. The columns that an older
+// version of the database does not have are read as their fallback.
+var columns = []struct {
+	name     string
+	fallback string
+}{
+	{name: "creation_utc"},
+	{name: "host_key"},
+	{name: "top_frame_site_key", fallback: "''"}, // Version 15.
+	{name: "name"},
+	{name: "value"},
+	{name: "encrypted_value"},
+	{name: "path"},
+	{name: "expires_utc"},
+	{name: "is_secure"},
+	{name: "is_httponly"},
+	{name: "last_access_utc"},
+	{name: "is_persistent"},
+	{name: "priority"},
+	{name: "samesite"},
+	{name: "source_scheme", fallback: "0"}, // Version 12.
+	{name: "source_port", fallback: "-1"},  // Version 13.
+}
+
+// webkitEpochOffset is the number of microseconds between 1601-01-01 and 1970-01-01.
+const webkitEpochOffset = 11644473600 * 1000000
+
+// Priority is the priority of a cookie.
+type Priority string
+
+// Priorities of the priority column of cookies.
+const (
+	PriorityLow    Priority = "Low"
+	PriorityMedium Priority = "Medium"
+	PriorityHigh   Priority = "High"
+)
+
+// Cookie is a cookie from the Cookies database.
+type Cookie struct {
+	cookiejar.Entry
+
+	// Priority is the priority of the cookie.
+	Priority Priority
+	// SourceScheme is "Unset", "NonSecure" or "Secure".
+	SourceScheme string
+	// SourcePort is the port of the URL the cookie was set from, -1 if unknown.
+	SourcePort int
+}
+
+// Importer is a cookie jar that can import entries, e.g. [cookiejar.Jar] and [cookiejar.PersistentJar].
+type Importer interface {
+	ImportEntries(entries ...cookiejar.Entry)
+}
+
+// Option configures the reader.
+type Option func(o *options)
+
+type options struct {
+	secret []byte
+}
+
+// WithKeyringSecret sets the secret that Chromium stored in the keyring, e.g. the "Chrome Safe Storage" password of
+// the GNOME keyring or KWallet. It is required for decrypting v11 values.
+func WithKeyringSecret(secret []byte) Option {
+	return func(o *options) {
+		o.secret = secret
+	}
+}
+
+// Import reads the cookies from the Cookies database at path and imports them to the jar.
+func Import(ctx context.Context, jar Importer, path string, opts ...Option) error {
+	entries, err := ReadEntries(ctx, path, opts...)
+	if err != nil {
+		return err
+	}
+
+	jar.ImportEntries(entries...)
+
+	return nil
+}
+
+// ReadEntries reads the cookies from the Cookies database at path, ordered by their creation time.
+func ReadEntries(ctx context.Context, path string, opts ...Option) ([]cookiejar.Entry, error) {
+	cookies, err := ReadCookies(ctx, path, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]cookiejar.Entry, len(cookies))
+
+	for i, c := range cookies {
+		entries[i] = c.Entry
+	}
+
+	return entries, nil
+}
+
+// ReadCookies reads the cookies from the Cookies database at path, ordered by their creation time. The encrypted values
+// are decrypted with the v10 fixed key or with the v11 keyring secret.
+func ReadCookies(ctx context.Context, path string, opts ...Option) ([]Cookie, error) {
+	o := options{}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	d, err := newDecrypter(o.secret)
+	if err != nil {
+		return nil, err
+	}
+
+	path, err = filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open chromium cookies database: %w", err)
+	}
+
+	db, err := sql.Open("sqlite", (&url.URL{Scheme: "file", Path: filepath.ToSlash(path), RawQuery: "mode=ro"}).String())
+	if err != nil {
+		return nil, fmt.Errorf("could not open chromium cookies database: %w", err)
+	}
+
+	defer db.Close() //nolint: errcheck
+
+	var version string
+
+	if err := db.QueryRowContext(ctx, `SELECT value FROM meta WHERE key = 'version'`).Scan(&version); err != nil {
+		return nil, fmt.Errorf("could not query chromium cookies database version: %w", err)
+	}
+
+	dbVersion, err := strconv.Atoi(version)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chromium cookies database version %q: %w", version, err)
+	}
+
+	query, err := buildQuery(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("could not query chromium cookies: %w", err)
+	}
+
+	defer rows.Close() //nolint: errcheck
+
+	var cookies []Cookie
+
+	for rows.Next() {
+		var (
+			hostKey, topFrameSiteKey, name, value, cookiePath string
+			encryptedValue                                    []byte
+			creationUTC, expiresUTC, lastAccessUTC            int64
+			isSecure, isHTTPOnly, isPersistent                bool
+			priority, sameSite, sourceScheme, sourcePort      int
+		)
+
+		if err := rows.Scan(&creationUTC, &hostKey, &topFrameSiteKey, &name, &value, &encryptedValue, &cookiePath,
+			&expiresUTC, &isSecure, &isHTTPOnly, &lastAccessUTC, &isPersistent, &priority, &sameSite,
+			&sourceScheme, &sourcePort,
+		); err != nil {
+			return nil, fmt.Errorf("could not read chromium cookie: %w", err)
+		}
+
+		if len(encryptedValue) > 0 {
+			if value, err = d.decrypt(encryptedValue, hostKey, dbVersion); err != nil {
+				return nil, fmt.Errorf("could not read chromium cookie %q for %q: %w", name, hostKey, err)
+			}
+		}
+
+		e := cookiejar.Entry{
+			Name:         name,
+			Value:        value,
+			Domain:       strings.ToLower(strings.TrimPrefix(hostKey, ".")),
+			Path:         cookiePath,
+			SameSite:     mapSameSite(sameSite),
+			Secure:       isSecure,
+			HttpOnly:     isHTTPOnly,
+			Persistent:   isPersistent,
+			HostOnly:     !strings.HasPrefix(hostKey, "."),
+			Partitioned:  topFrameSiteKey != "",
+			PartitionKey: topFrameSiteKey,
+			Creation:     fromWebKitTime(creationUTC),
+			LastAccess:   fromWebKitTime(lastAccessUTC),
+			SeqNum:       uint64(len(cookies)),
+		}
+
+		if isPersistent {
+			e.Expires = fromWebKitTime(expiresUTC)
+		}
+
+		cookies = append(cookies, Cookie{
+			Entry:        e,
+			Priority:     mapPriority(priority),
+			SourceScheme: mapSourceScheme(sourceScheme),
+			SourcePort:   sourcePort,
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not read chromium cookies: %w", err)
+	}
+
+	return cookies, nil
+}
+
+// buildQuery returns the query of the cookies with the columns of the table, see columns.
+func buildQuery(ctx context.Context, db *sql.DB) (string, error) {
+	rows, err := db.QueryContext(ctx, `SELECT name FROM pragma_table_info('cookies')`)
+	if err != nil {
+		return "", fmt.Errorf("could not query chromium cookies columns: %w", err)
+	}
+
+	defer rows.Close() //nolint: errcheck
+
+	existing := make(map[string]bool)
+
+	for rows.Next() {
+		var name string
+
+		if err := rows.Scan(&name); err != nil {
+			return "", fmt.Errorf("could not read chromium cookies columns: %w", err)
+		}
+
+		existing[name] = true
+	}
+
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("could not read chromium cookies columns: %w", err)
+	}
+
+	if len(existing) == 0 {
+		return "", errors.New("could not query chromium cookies: no cookies table")
+	}
+
+	selected := make([]string, len(columns))
+
+	for i, c := range columns {
+		if existing[c.name] || c.fallback == "" {
+			selected[i] = c.name
+		} else {
+			selected[i] = c.fallback + " AS " + c.name
+		}
+	}
+
+	return "SELECT " + strings.Join(selected, ", ") + " FROM cookies ORDER BY creation_utc", nil
+}
+
+// fromWebKitTime converts microseconds since 1601-01-01 UTC to time. 0 is the zero time.
+func fromWebKitTime(t int64) time.Time {
+	if t == 0 {
+		return time.Time{}
+	}
+
+	return time.UnixMicro(t - webkitEpochOffset).UTC()
+}
+
+func mapSameSite(sameSite int) string {
+	switch sameSite {
+	case sameSiteLax:
+		return "SameSite=Lax"
+
+	case sameSiteStrict:
+		return "SameSite=Strict"
+
+	default:
+		return ""
+	}
+}
+
+func mapPriority(priority int) Priority {
+	switch priority {
+	case 0:
+		return PriorityLow
+
+	case 2: //nolint: mnd
+		return PriorityHigh
+
+	default:
+		return PriorityMedium
+	}
+}
+
+func mapSourceScheme(sourceScheme int) string {
+	switch sourceScheme {
+	case 1:
+		return "NonSecure"
+
+	case 2: //nolint: mnd
+		return "Secure"
+
+	default:
+		return "Unset"
+	}
+}
@@ -0,0 +1,176 @@
+package chromium_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.nhat.io/cookiejar"
+	"go.nhat.io/cookiejar/chromium"
+)
+
+var secret = []byte("s3cret")
+
+func TestReadCookies(t *testing.T) {
+	t.Parallel()
+
+	expires := time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+	expected := []chromium.Cookie{
+		{
+			Entry: cookiejar.Entry{
+				Name:       "session",
+				Value:      "abc",
+				Domain:     "www.example.com",
+				Path:       "/",
+				SameSite:   "SameSite=Strict",
+				Secure:     true,
+				HttpOnly:   true,
+				HostOnly:   true,
+				Creation:   time.Unix(1700000000, 0).UTC(),
+				LastAccess: time.Unix(1700000001, 0).UTC(),
+				SeqNum:     0,
+			},
+			Priority:     chromium.PriorityHigh,
+			SourceScheme: "Secure",
+			SourcePort:   443,
+		},
+		{
+			Entry: cookiejar.Entry{
+				Name:       "theme",
+				Value:      "dark",
+				Domain:     "example.com",
+				Path:       "/app",
+				SameSite:   "SameSite=Lax",
+				Persistent: true,
+				Expires:    expires,
+				Creation:   time.Unix(1700000002, 0).UTC(),
+				LastAccess: time.Unix(1700000003, 0).UTC(),
+				SeqNum:     1,
+			},
+			Priority:     chromium.PriorityMedium,
+			SourceScheme: "NonSecure",
+			SourcePort:   80,
+		},
+		{
+			Entry: cookiejar.Entry{
+				Name:         "embed",
+				Value:        "plain",
+				Domain:       "widget.example.net",
+				Path:         "/",
+				Secure:       true,
+				Persistent:   true,
+				HostOnly:     true,
+				Partitioned:  true,
+				PartitionKey: "https://example.org",
+				Expires:      expires,
+				Creation:     time.Unix(1700000004, 0).UTC(),
+				LastAccess:   time.Unix(1700000005, 0).UTC(),
+				SeqNum:       2,
+			},
+			Priority:     chromium.PriorityLow,
+			SourceScheme: "Secure",
+			SourcePort:   -1,
+		},
+	}
+
+	// Version 11 has no partition key, source scheme and source port.
+	legacy := slices.Clone(expected)
+
+	for i := range legacy {
+		legacy[i].Partitioned = false
+		legacy[i].PartitionKey = ""
+		legacy[i].SourceScheme = "Unset"
+		legacy[i].SourcePort = -1
+	}
+
+	testCases := []struct {
+		scenario string
+		path     string
+		expected []chromium.Cookie
+	}{
+		{
+			scenario: "with domain hash prefix",
+			path:     "testdata/Cookies",
+			expected: expected,
+		},
+		{
+			scenario: "without domain hash prefix",
+			path:     "testdata/Cookies-v20",
+			expected: expected,
+		},
+		{
+			scenario: "without the newer columns",
+			path:     "testdata/Cookies-v11",
+			expected: legacy,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := chromium.ReadCookies(context.Background(), tc.path, chromium.WithKeyringSecret(secret))
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestReadCookies_Error(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario      string
+		path          string
+		options       []chromium.Option
+		expectedError error
+	}{
+		{
+			scenario:      "missing secret",
+			path:          "testdata/Cookies",
+			expectedError: chromium.ErrMissingSecret,
+		},
+		{
+			scenario:      "wrong secret",
+			path:          "testdata/Cookies",
+			options:       []chromium.Option{chromium.WithKeyringSecret([]byte("wrong"))},
+			expectedError: chromium.ErrDecrypt,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := chromium.ReadCookies(context.Background(), tc.path, tc.options...)
+
+			assert.Nil(t, actual)
+			assert.ErrorIs(t, err, tc.expectedError)
+		})
+	}
+}
+
+func TestImport(t *testing.T) {
+	t.Parallel()
+
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+
+	err = chromium.Import(context.Background(), jar, "testdata/Cookies", chromium.WithKeyringSecret(secret))
+	require.NoError(t, err)
+
+	actual := jar.Cookies(&url.URL{Scheme: "https", Host: "www.example.com", Path: "/app/"})
+	expected := []*http.Cookie{
+		{Name: "theme", Value: "dark"},
+		{Name: "session", Value: "abc"},
+	}
+
+	assert.Equal(t, expected, actual)
+}
@@ -0,0 +1,126 @@
+package chromium
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha1" //nolint: gosec // Chromium derives the key with PBKDF2-SHA1.
+	"crypto/sha256"
+	"errors"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	prefixV10 = "v10"
+	prefixV11 = "v11"
+
+	// hashPrefixVersion is the version of the database from which the decrypted values are prefixed with the SHA-256
+	// of the host key.
+	hashPrefixVersion = 24
+)
+
+var (
+	// ErrMissingSecret indicates that a v11 value is found but the keyring secret is not provided.
+	ErrMissingSecret = errors.New("chromium: keyring secret is required for v11 values")
+	// ErrDecrypt indicates that a value could not be decrypted, usually because of a wrong keyring secret.
+	ErrDecrypt = errors.New("chromium: could not decrypt value")
+)
+
+// v10Password is the fixed password used when no keyring is available.
+var v10Password = []byte("peanuts")
+
+// decrypter decrypts the encrypted_value column with the v10/v11 AES-128-CBC scheme of Chromium on Linux.
+type decrypter struct {
+	v10 cipher.Block
+	v11 cipher.Block
+}
+
+func newDecrypter(secret []byte) (*decrypter, error) {
+	v10, err := aes.NewCipher(deriveKey(v10Password))
+	if err != nil {
+		return nil, err
+	}
+
+	d := &decrypter{v10: v10}
+
+	if secret != nil {
+		if d.v11, err = aes.NewCipher(deriveKey(secret)); err != nil {
+			return nil, err
+		}
+	}
+
+	return d, nil
+}
+
+// decrypt decrypts value of a cookie for hostKey. The SHA-256 of hostKey that prefixes the value in newer databases is
+// verified and removed.
+func (d *decrypter) decrypt(value []byte, hostKey string, dbVersion int) (string, error) {
+	var block cipher.Block
+
+	switch {
+	case bytes.HasPrefix(value, []byte(prefixV10)):
+		block = d.v10
+
+	case bytes.HasPrefix(value, []byte(prefixV11)):
+		if d.v11 == nil {
+			return "", ErrMissingSecret
+		}
+
+		block = d.v11
+
+	default:
+		// Not encrypted.
+		return string(value), nil
+	}
+
+	ciphertext := value[len(prefixV10):]
+	if len(ciphertext) == 0 || len(ciphertext)%aes.BlockSize != 0 {
+		return "", ErrDecrypt
+	}
+
+	plaintext := make([]byte, len(ciphertext))
+
+	cipher.NewCBCDecrypter(block, bytes.Repeat([]byte{' '}, aes.BlockSize)).CryptBlocks(plaintext, ciphertext)
+
+	plaintext, ok := unpad(plaintext)
+	if !ok {
+		return "", ErrDecrypt
+	}
+
+	if dbVersion >= hashPrefixVersion {
+		hash := sha256.Sum256([]byte(hostKey))
+
+		if !bytes.HasPrefix(plaintext, hash[:]) {
+			return "", ErrDecrypt
+		}
+
+		plaintext = plaintext[len(hash):]
+	}
+
+	return string(plaintext), nil
+}
+
+func deriveKey(password []byte) []byte {
+	return pbkdf2.Key(password, []byte("saltysalt"), 1, 16, sha1.New)
+}
+
+// unpad removes the PKCS#7 padding.
+func unpad(b []byte) ([]byte, bool) {
+	if len(b) == 0 {
+		return nil, false
+	}
+
+	n := int(b[len(b)-1])
+	if n == 0 || n > aes.BlockSize || n > len(b) {
+		return nil, false
+	}
+
+	for _, c := range b[len(b)-n:] {
+		if int(c) != n {
+			return nil, false
+		}
+	}
+
+	return b[:len(b)-n], true
+}
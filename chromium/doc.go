@@ -0,0 +1,3 @@
+// Package chromium imports cookies from the Cookies database of a Chrome or Chromium profile on Linux. The databases
+// from version 11 on are supported, the columns added since then are read with their default value when missing.
+package chromium
@@ -0,0 +1,110 @@
+package cookiejar
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+var _ EntrySerDer = (*compressedSerDer)(nil)
+
+// compressedSerDer compresses the output of another serializer.
+type compressedSerDer struct {
+	serder   EntrySerDer
+	compress func(w io.Writer) (io.WriteCloser, error)
+}
+
+// NewGzipSerDer returns a serializer/deserializer that compresses the output of serder with gzip. A nil serder is the
+// default JSON serializer/deserializer.
+//
+// The format is detected from the magic bytes when deserializing, so gzip, zstd and uncompressed files are all loaded,
+// and are written with gzip on the next Sync.
+func NewGzipSerDer(serder EntrySerDer) EntrySerDer {
+	return newCompressedSerDer(serder, func(w io.Writer) (io.WriteCloser, error) {
+		return gzip.NewWriter(w), nil
+	})
+}
+
+// NewZstdSerDer returns a serializer/deserializer that compresses the output of serder with zstd. A nil serder is the
+// default JSON serializer/deserializer.
+//
+// The format is detected from the magic bytes when deserializing, so gzip, zstd and uncompressed files are all loaded,
+// and are written with zstd on the next Sync.
+func NewZstdSerDer(serder EntrySerDer) EntrySerDer {
+	return newCompressedSerDer(serder, func(w io.Writer) (io.WriteCloser, error) {
+		return zstd.NewWriter(w)
+	})
+}
+
+func newCompressedSerDer(serder EntrySerDer, compress func(w io.Writer) (io.WriteCloser, error)) EntrySerDer {
+	if serder == nil {
+		serder = jsonSerDer{}
+	}
+
+	return &compressedSerDer{
+		serder:   serder,
+		compress: compress,
+	}
+}
+
+func (s *compressedSerDer) copySerDer() EntrySerDer {
+	c := *s
+	c.serder = copySerDer(s.serder)
+
+	return &c
+}
+
+func (s *compressedSerDer) Serialize(w io.Writer, entries map[string]map[string]Entry) error {
+	cw, err := s.compress(w)
+	if err != nil {
+		return err
+	}
+
+	if err := s.serder.Serialize(cw, entries); err != nil {
+		_ = cw.Close() //nolint: errcheck
+
+		return err
+	}
+
+	return cw.Close()
+}
+
+func (s *compressedSerDer) Deserialize(r io.Reader) (map[string]map[string]Entry, error) {
+	br := bufio.NewReader(r)
+
+	// A short file is not compressed, Peek returns what there is.
+	magic, _ := br.Peek(len(zstdMagic)) //nolint: errcheck
+
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+
+		defer gr.Close() //nolint: errcheck
+
+		return s.serder.Deserialize(gr)
+
+	case bytes.HasPrefix(magic, zstdMagic):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+
+		defer zr.Close()
+
+		return s.serder.Deserialize(zr)
+
+	default:
+		return s.serder.Deserialize(br)
+	}
+}
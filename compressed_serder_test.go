@@ -0,0 +1,122 @@
+package cookiejar_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.nhat.io/cookiejar"
+)
+
+func TestCompressedSerDer(t *testing.T) {
+	t.Parallel()
+
+	entries := map[string]map[string]cookiejar.Entry{
+		"example.com": {
+			"example.com;/;id": {Name: "id", Value: "42", Domain: "example.com", Path: "/", HostOnly: true},
+		},
+	}
+
+	testCases := []struct {
+		scenario      string
+		serder        cookiejar.EntrySerDer
+		expectedMagic []byte
+	}{
+		{
+			scenario:      "gzip",
+			serder:        cookiejar.NewGzipSerDer(nil),
+			expectedMagic: []byte{0x1f, 0x8b},
+		},
+		{
+			scenario:      "zstd",
+			serder:        cookiejar.NewZstdSerDer(nil),
+			expectedMagic: []byte{0x28, 0xb5, 0x2f, 0xfd},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+
+			require.NoError(t, tc.serder.Serialize(&buf, entries))
+			assert.True(t, bytes.HasPrefix(buf.Bytes(), tc.expectedMagic))
+
+			actual, err := tc.serder.Deserialize(&buf)
+			require.NoError(t, err)
+
+			assert.Equal(t, entries, actual)
+		})
+	}
+}
+
+func TestCompressedSerDer_Detect(t *testing.T) {
+	t.Parallel()
+
+	entries := map[string]map[string]cookiejar.Entry{
+		"example.com": {
+			"example.com;/;id": {Name: "id", Value: "42", Domain: "example.com", Path: "/", HostOnly: true},
+		},
+	}
+
+	var gz, zst bytes.Buffer
+
+	require.NoError(t, cookiejar.NewGzipSerDer(nil).Serialize(&gz, entries))
+	require.NoError(t, cookiejar.NewZstdSerDer(nil).Serialize(&zst, entries))
+
+	testCases := []struct {
+		scenario string
+		data     []byte
+	}{
+		{scenario: "gzip", data: gz.Bytes()},
+		{scenario: "zstd", data: zst.Bytes()},
+		{scenario: "plain", data: []byte(`{"example.com":{"example.com;/;id":{"Name":"id","Value":"42","Domain":"example.com","Path":"/","HostOnly":true}}}`)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			for _, serder := range []cookiejar.EntrySerDer{cookiejar.NewGzipSerDer(nil), cookiejar.NewZstdSerDer(nil)} {
+				actual, err := serder.Deserialize(bytes.NewReader(tc.data))
+				require.NoError(t, err)
+
+				assert.Equal(t, entries, actual)
+			}
+		})
+	}
+}
+
+func TestCompressedSerDer_Upgrade(t *testing.T) {
+	t.Parallel()
+
+	const filePath = "/tmp/cookies.json"
+
+	fs := afero.NewMemMapFs()
+	u := &url.URL{Scheme: "https", Host: "example.com", Path: "/"}
+
+	// A plain JSON file is loaded and written compressed on the next sync.
+	plain := cookiejar.NewPersistentJar(cookiejar.WithFs(fs), cookiejar.WithFilePath(filePath))
+	plain.SetCookies(u, []*http.Cookie{{Name: "id", Value: "42"}})
+	require.NoError(t, plain.Sync())
+
+	j := cookiejar.NewPersistentJar(
+		cookiejar.WithFs(fs),
+		cookiejar.WithFilePath(filePath),
+		cookiejar.WithSerDer(cookiejar.NewZstdSerDer(nil)),
+	)
+
+	assert.Equal(t, []*http.Cookie{{Name: "id", Value: "42"}}, j.Cookies(u))
+	require.NoError(t, j.Sync())
+
+	data, err := afero.ReadFile(fs, filePath)
+	require.NoError(t, err)
+
+	assert.True(t, bytes.HasPrefix(data, []byte{0x28, 0xb5, 0x2f, 0xfd}))
+}
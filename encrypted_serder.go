@@ -0,0 +1,255 @@
+package cookiejar
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	encryptionKeySize   = 32
+	encryptionKeyIDSize = 8
+)
+
+// encryptionMagic is the header of the encrypted files, followed by the key id, the nonce and the ciphertext.
+var encryptionMagic = []byte("CJENC1")
+
+var (
+	// ErrWrongKey indicates that none of the keys can decrypt the cookies.
+	ErrWrongKey = errors.New("cookiejar: wrong encryption key")
+	// ErrCorruptData indicates that the encrypted cookies are damaged or have been tampered with.
+	ErrCorruptData = errors.New("cookiejar: corrupt encrypted data")
+
+	errInvalidKeySize = fmt.Errorf("cookiejar: encryption key must be %d bytes", encryptionKeySize)
+	errNoKeys         = errors.New("cookiejar: no encryption keys")
+)
+
+var _ EntrySerDer = (*encryptedSerDer)(nil)
+
+// KeyProvider provides the keys for encrypting the cookies at rest.
+type KeyProvider interface {
+	// Keys returns the 32-byte encryption keys. The first key is used for encrypting, all of them are tried for
+	// decrypting. Data encrypted with a previous key is therefore re-encrypted with the first key on the next Sync.
+	Keys() ([][]byte, error)
+}
+
+// KeyProviderFunc is an adapter to allow the use of ordinary functions as KeyProvider.
+type KeyProviderFunc func() ([][]byte, error)
+
+// Keys returns f().
+func (f KeyProviderFunc) Keys() ([][]byte, error) {
+	return f()
+}
+
+// RawKey provides a raw 32-byte key.
+func RawKey(key []byte) KeyProvider {
+	return KeyProviderFunc(func() ([][]byte, error) {
+		if len(key) != encryptionKeySize {
+			return nil, errInvalidKeySize
+		}
+
+		return [][]byte{key}, nil
+	})
+}
+
+// PassphraseKey provides a key derived from a passphrase and a salt with scrypt. The key is derived only once.
+func PassphraseKey(passphrase string, salt []byte) KeyProvider {
+	derive := sync.OnceValues(func() ([]byte, error) {
+		return scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, encryptionKeySize)
+	})
+
+	return KeyProviderFunc(func() ([][]byte, error) {
+		key, err := derive()
+		if err != nil {
+			return nil, err
+		}
+
+		return [][]byte{key}, nil
+	})
+}
+
+// EnvKey provides a base64-encoded 32-byte key from an environment variable. The variable is read every time the keys
+// are needed.
+func EnvKey(name string) KeyProvider {
+	return KeyProviderFunc(func() ([][]byte, error) {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return nil, fmt.Errorf("cookiejar: environment variable %s is not set", name)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("cookiejar: could not decode key from environment variable %s: %w", name, err)
+		}
+
+		return RawKey(key).Keys()
+	})
+}
+
+// RotateKeys provides the keys of current for encrypting and the keys of current and previous for decrypting.
+func RotateKeys(current KeyProvider, previous ...KeyProvider) KeyProvider {
+	return KeyProviderFunc(func() ([][]byte, error) {
+		var keys [][]byte
+
+		for _, p := range append([]KeyProvider{current}, previous...) {
+			k, err := p.Keys()
+			if err != nil {
+				return nil, err
+			}
+
+			keys = append(keys, k...)
+		}
+
+		return keys, nil
+	})
+}
+
+// encryptedSerDer encrypts the output of another serializer with AES-256-GCM.
+type encryptedSerDer struct {
+	serder EntrySerDer
+	keys   KeyProvider
+}
+
+// NewEncryptedSerDer returns a serializer/deserializer that encrypts the output of serder with AES-256-GCM. A nil serder
+// is the default JSON serializer/deserializer.
+func NewEncryptedSerDer(serder EntrySerDer, keys KeyProvider) EntrySerDer {
+	if serder == nil {
+		serder = jsonSerDer{}
+	}
+
+	return &encryptedSerDer{
+		serder: serder,
+		keys:   keys,
+	}
+}
+
+func (s *encryptedSerDer) copySerDer() EntrySerDer {
+	c := *s
+	c.serder = copySerDer(s.serder)
+
+	return &c
+}
+
+func (s *encryptedSerDer) Serialize(w io.Writer, entries map[string]map[string]Entry) error {
+	keys, err := providedKeys(s.keys)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+
+	if err := s.serder.Serialize(&buf, entries); err != nil {
+		return err
+	}
+
+	aead, err := newAEAD(keys[0])
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	header := make([]byte, 0, len(encryptionMagic)+encryptionKeyIDSize+len(nonce))
+	header = append(header, encryptionMagic...)
+	header = append(header, keyID(keys[0])...)
+	header = append(header, nonce...)
+
+	_, err = w.Write(aead.Seal(header, nonce, buf.Bytes(), header[:len(encryptionMagic)+encryptionKeyIDSize]))
+
+	return err
+}
+
+func (s *encryptedSerDer) Deserialize(r io.Reader) (map[string]map[string]Entry, error) {
+	keys, err := providedKeys(s.keys)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	headerSize := len(encryptionMagic) + encryptionKeyIDSize
+
+	if len(data) < headerSize || !bytes.Equal(data[:len(encryptionMagic)], encryptionMagic) {
+		return nil, ErrCorruptData
+	}
+
+	id := data[len(encryptionMagic):headerSize]
+
+	for _, key := range keys {
+		if !bytes.Equal(keyID(key), id) {
+			continue
+		}
+
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(data) < headerSize+aead.NonceSize() {
+			return nil, ErrCorruptData
+		}
+
+		nonce := data[headerSize : headerSize+aead.NonceSize()]
+
+		plaintext, err := aead.Open(nil, nonce, data[headerSize+aead.NonceSize():], data[:headerSize])
+		if err != nil {
+			return nil, ErrCorruptData
+		}
+
+		return s.serder.Deserialize(bytes.NewReader(plaintext))
+	}
+
+	return nil, ErrWrongKey
+}
+
+// providedKeys returns the keys of p after checking them.
+func providedKeys(p KeyProvider) ([][]byte, error) {
+	keys, err := p.Keys()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(keys) == 0 {
+		return nil, errNoKeys
+	}
+
+	for _, key := range keys {
+		if len(key) != encryptionKeySize {
+			return nil, errInvalidKeySize
+		}
+	}
+
+	return keys, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// keyID identifies a key without revealing it, so that a wrong key can be told apart from corrupt data.
+func keyID(key []byte) []byte {
+	sum := sha256.Sum256(append([]byte("cookiejar key id:"), key...))
+
+	return sum[:encryptionKeyIDSize]
+}
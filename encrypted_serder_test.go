@@ -0,0 +1,240 @@
+package cookiejar_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.nhat.io/cookiejar"
+)
+
+var (
+	oldKey = bytes.Repeat([]byte{1}, 32)
+	newKey = bytes.Repeat([]byte{2}, 32)
+)
+
+func TestEncryptedSerDer_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	entries := map[string]map[string]cookiejar.Entry{
+		"example.com": {
+			"example.com;/;id": {
+				Name:     "id",
+				Value:    "42",
+				Domain:   "example.com",
+				Path:     "/",
+				HostOnly: true,
+				Expires:  time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC),
+			},
+		},
+	}
+
+	testCases := []struct {
+		scenario string
+		keys     cookiejar.KeyProvider
+	}{
+		{
+			scenario: "raw key",
+			keys:     cookiejar.RawKey(newKey),
+		},
+		{
+			scenario: "passphrase",
+			keys:     cookiejar.PassphraseKey("correct horse battery staple", []byte("salt")),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			s := cookiejar.NewEncryptedSerDer(nil, tc.keys)
+
+			var buf bytes.Buffer
+
+			err := s.Serialize(&buf, entries)
+			require.NoError(t, err)
+
+			assert.NotContains(t, buf.String(), "example.com")
+
+			actual, err := s.Deserialize(&buf)
+			require.NoError(t, err)
+
+			assert.Equal(t, entries, actual)
+		})
+	}
+}
+
+func TestEncryptedSerDer_Deserialize_Error(t *testing.T) {
+	t.Parallel()
+
+	var encrypted bytes.Buffer
+
+	err := cookiejar.NewEncryptedSerDer(nil, cookiejar.RawKey(oldKey)).
+		Serialize(&encrypted, map[string]map[string]cookiejar.Entry{})
+	require.NoError(t, err)
+
+	tampered := bytes.Clone(encrypted.Bytes())
+	tampered[len(tampered)-1] ^= 0xff
+
+	testCases := []struct {
+		scenario      string
+		data          []byte
+		keys          cookiejar.KeyProvider
+		expectedError error
+	}{
+		{
+			scenario:      "wrong key",
+			data:          encrypted.Bytes(),
+			keys:          cookiejar.RawKey(newKey),
+			expectedError: cookiejar.ErrWrongKey,
+		},
+		{
+			scenario:      "tampered data",
+			data:          tampered,
+			keys:          cookiejar.RawKey(oldKey),
+			expectedError: cookiejar.ErrCorruptData,
+		},
+		{
+			scenario:      "truncated data",
+			data:          encrypted.Bytes()[:10],
+			keys:          cookiejar.RawKey(oldKey),
+			expectedError: cookiejar.ErrCorruptData,
+		},
+		{
+			scenario:      "not encrypted",
+			data:          []byte(`{}`),
+			keys:          cookiejar.RawKey(oldKey),
+			expectedError: cookiejar.ErrCorruptData,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := cookiejar.NewEncryptedSerDer(nil, tc.keys).Deserialize(bytes.NewReader(tc.data))
+
+			assert.Nil(t, actual)
+			assert.ErrorIs(t, err, tc.expectedError)
+		})
+	}
+}
+
+func TestEncryptedSerDer_InvalidKey(t *testing.T) {
+	t.Parallel()
+
+	s := cookiejar.NewEncryptedSerDer(nil, cookiejar.RawKey([]byte("short")))
+
+	err := s.Serialize(&bytes.Buffer{}, nil)
+
+	assert.EqualError(t, err, "cookiejar: encryption key must be 32 bytes")
+}
+
+func TestEnvKey(t *testing.T) {
+	t.Setenv("COOKIEJAR_TEST_KEY", base64.StdEncoding.EncodeToString(newKey))
+
+	keys, err := cookiejar.EnvKey("COOKIEJAR_TEST_KEY").Keys()
+	require.NoError(t, err)
+
+	assert.Equal(t, [][]byte{newKey}, keys)
+
+	_, err = cookiejar.EnvKey("COOKIEJAR_TEST_MISSING_KEY").Keys()
+
+	assert.EqualError(t, err, "cookiejar: environment variable COOKIEJAR_TEST_MISSING_KEY is not set")
+}
+
+func TestPersistentJar_EncryptedSerDer_RotateKeys(t *testing.T) {
+	t.Parallel()
+
+	const filePath = "/tmp/cookies.enc"
+
+	fs := afero.NewMemMapFs()
+	u := &url.URL{Scheme: "https", Host: "example.com"}
+
+	j := cookiejar.NewPersistentJar(
+		cookiejar.WithFs(fs),
+		cookiejar.WithFilePath(filePath),
+		cookiejar.WithSerDer(cookiejar.NewEncryptedSerDer(nil, cookiejar.RawKey(oldKey))),
+	)
+
+	j.SetCookies(u, []*http.Cookie{{Name: "id", Value: "42"}})
+
+	require.NoError(t, j.Sync())
+
+	// Load with the new key while the old one is still accepted, then sync to re-encrypt.
+	j = cookiejar.NewPersistentJar(
+		cookiejar.WithFs(fs),
+		cookiejar.WithFilePath(filePath),
+		cookiejar.WithSerDer(cookiejar.NewEncryptedSerDer(nil,
+			cookiejar.RotateKeys(cookiejar.RawKey(newKey), cookiejar.RawKey(oldKey)),
+		)),
+	)
+
+	assert.Equal(t, []*http.Cookie{{Name: "id", Value: "42"}}, j.Cookies(u))
+	require.NoError(t, j.Sync())
+
+	data, err := afero.ReadFile(fs, filePath)
+	require.NoError(t, err)
+
+	_, err = cookiejar.NewEncryptedSerDer(nil, cookiejar.RawKey(oldKey)).Deserialize(bytes.NewReader(data))
+	require.ErrorIs(t, err, cookiejar.ErrWrongKey)
+
+	_, err = cookiejar.NewEncryptedSerDer(nil, cookiejar.RawKey(newKey)).Deserialize(bytes.NewReader(data))
+	require.NoError(t, err)
+}
+
+func TestPersistentJar_EncryptedSerDer_WrongKey(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario string
+		opts     []cookiejar.PersistentJarOption
+	}{
+		{scenario: "file"},
+		{scenario: "journal", opts: []cookiejar.PersistentJarOption{cookiejar.WithJournal(1, 0)}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			const filePath = "/tmp/cookies.enc"
+
+			fs := afero.NewMemMapFs()
+			u := &url.URL{Scheme: "https", Host: "example.com"}
+
+			newJar := func(key []byte, opts ...cookiejar.PersistentJarOption) *cookiejar.PersistentJar {
+				return cookiejar.NewPersistentJar(append([]cookiejar.PersistentJarOption{
+					cookiejar.WithFs(fs),
+					cookiejar.WithFilePath(filePath),
+					cookiejar.WithSerDer(cookiejar.NewEncryptedSerDer(nil, cookiejar.RawKey(key))),
+				}, opts...)...)
+			}
+
+			j := newJar(oldKey)
+
+			j.SetCookies(u, []*http.Cookie{{Name: "id", Value: "42"}, {Name: "lang", Value: "en"}})
+
+			require.NoError(t, j.Close())
+
+			// The jar with the wrong key does not overwrite the cookies.
+			j = newJar(newKey, append(tc.opts, cookiejar.WithAutoSync(true))...)
+
+			j.SetCookies(u, []*http.Cookie{{Name: "theme", Value: "dark"}})
+
+			require.ErrorIs(t, j.Sync(), cookiejar.ErrWrongKey)
+			require.ErrorIs(t, j.Close(), cookiejar.ErrWrongKey)
+
+			j = newJar(oldKey)
+
+			assert.Equal(t, []*http.Cookie{{Name: "id", Value: "42"}, {Name: "lang", Value: "en"}}, j.Cookies(u))
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package cookiejar
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ImportEntries adds the entries to the jar, replacing the stored ones with the same domain, path and name.
+//
+// The entries are keyed with the public suffix list of the jar and are given new sequence numbers in the order they
+// are passed. Expired entries and entries that are illegal with the public suffix list are skipped.
+func (j *Jar) ImportEntries(entries ...Entry) {
+	j.importEntries(entries, time.Now())
+}
+
+// importEntries is like ImportEntries but takes the current time as a parameter.
+func (j *Jar) importEntries(entries []Entry, now time.Time) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for _, e := range entries {
+		if e.Persistent && !e.Expires.After(now) {
+			continue
+		}
+
+		if !isLegalDomain(e.Domain, e.HostOnly, j.psList) {
+			continue
+		}
+
+		key := jarKey(e.Domain, j.psList)
+		id := e.ID()
+		imported := importEntry(e)
+
+		// An entry of a sealed file is already sealed.
+		if !isSealed(imported.Value) {
+			if err := j.sealer.seal(&imported); err != nil {
+				j.storeError(err)
+
+				continue
+			}
+		}
+
+		if !imported.Persistent {
+			imported.Expires = endOfTime
+		}
+
+		j.updateSubmap(key, "", now, func(submap map[string]entry) bool {
+			if old, ok := submap[id]; ok {
+				imported.seqNum = old.seqNum
+
+				if imported.Creation.IsZero() {
+					imported.Creation = old.Creation
+				}
+			} else {
+				imported.seqNum = j.nextSeqNum
+				j.nextSeqNum++
+			}
+
+			if imported.Creation.IsZero() {
+				imported.Creation = now
+			}
+
+			if imported.LastAccess.IsZero() {
+				imported.LastAccess = imported.Creation
+			}
+
+			submap[id] = imported
+
+			return true
+		})
+	}
+}
+
+// Entries returns all the entries in the jar, including the expired ones that have not been removed yet, ordered by
+// their sequence number. The values of the sealed cookies are SealedValue.
+func (j *Jar) Entries() []Entry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entries, err := j.allEntries()
+	if err != nil {
+		j.storeError(err)
+
+		return nil
+	}
+
+	result := sortedEntries(mapToExport(entries))
+
+	for i, e := range result {
+		result[i] = j.sealer.redact(e)
+	}
+
+	return result
+}
+
+// HTTPCookie rebuilds the complete cookie, as in the Set-Cookie header that created the entry. The Domain is blank for
+// a host-only cookie and Expires is set only for a persistent cookie.
+func (e Entry) HTTPCookie() *http.Cookie {
+	c := &http.Cookie{
+		Name:        e.Name,
+		Value:       e.Value,
+		Quoted:      e.Quoted,
+		Path:        e.Path,
+		Secure:      e.Secure,
+		HttpOnly:    e.HttpOnly,
+		Partitioned: e.Partitioned,
+	}
+
+	if !e.HostOnly {
+		c.Domain = e.Domain
+	}
+
+	if e.Persistent {
+		c.Expires = e.Expires
+	}
+
+	switch e.SameSite {
+	case "SameSite":
+		c.SameSite = http.SameSiteDefaultMode
+
+	case "SameSite=Strict":
+		c.SameSite = http.SameSiteStrictMode
+
+	case "SameSite=Lax":
+		c.SameSite = http.SameSiteLaxMode
+	}
+
+	return c
+}
+
+// ExportSetCookies returns the complete cookies of domain and its subdomains, ordered by their sequence number, so that
+// they can be written to an [http.ResponseWriter] with [http.SetCookie]. An empty domain exports all the cookies.
+// Expired cookies are not exported, nor the sealed ones, whose value is only sent by Cookies.
+func (j *Jar) ExportSetCookies(domain string) []*http.Cookie {
+	return j.exportSetCookies(domain, time.Now())
+}
+
+// exportSetCookies is like ExportSetCookies but takes the current time as a parameter.
+func (j *Jar) exportSetCookies(domain string, now time.Time) []*http.Cookie {
+	if domain != "" {
+		var err error
+
+		if domain, err = canonicalHost(strings.TrimPrefix(domain, ".")); err != nil {
+			return nil
+		}
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entries, err := j.allEntries()
+	if err != nil {
+		j.storeError(err)
+
+		return nil
+	}
+
+	var cookies []*http.Cookie
+
+	for _, e := range sortedEntries(mapToExport(entries)) {
+		if e.Persistent && !e.Expires.After(now) {
+			continue
+		}
+
+		if j.sealer.hides(e) {
+			continue
+		}
+
+		if domain != "" && e.Domain != domain && !hasDotSuffix(e.Domain, domain) {
+			continue
+		}
+
+		cookies = append(cookies, e.HTTPCookie())
+	}
+
+	return cookies
+}
+
+// ImportEntries adds the entries to the jar, see [Jar.ImportEntries].
+func (j *PersistentJar) ImportEntries(entries ...Entry) {
+	j.lazyLoad.Do(j.load)
+	j.jar.ImportEntries(entries...)
+	j.syncIfAuto()
+}
+
+// ExportSetCookies returns the complete cookies of domain and its subdomains, see [Jar.ExportSetCookies].
+func (j *PersistentJar) ExportSetCookies(domain string) []*http.Cookie {
+	j.lazyLoad.Do(j.load)
+
+	return j.jar.ExportSetCookies(domain)
+}
+
+// Entries returns all the entries in the jar, see [Jar.Entries].
+func (j *PersistentJar) Entries() []Entry {
+	j.lazyLoad.Do(j.load)
+
+	return j.jar.Entries()
+}
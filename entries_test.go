@@ -0,0 +1,139 @@
+package cookiejar_test
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.nhat.io/cookiejar"
+)
+
+func TestJar_ImportEntries(t *testing.T) {
+	t.Parallel()
+
+	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: suffixList{"co.uk"}})
+	require.NoError(t, err)
+
+	jar.ImportEntries(
+		cookiejar.Entry{Name: "id", Value: "41", Domain: "www.example.co.uk", Path: "/", HostOnly: true},
+		cookiejar.Entry{Name: "theme", Value: "dark", Domain: "example.co.uk", Path: "/"},
+		cookiejar.Entry{Name: "expired", Value: "1", Domain: "example.co.uk", Path: "/", Persistent: true, Expires: time.Unix(1, 0)},
+		cookiejar.Entry{Name: "tracker", Value: "1", Domain: "co.uk", Path: "/"},
+		cookiejar.Entry{Name: "id", Value: "42", Domain: "www.example.co.uk", Path: "/", HostOnly: true},
+	)
+
+	actual := jar.Cookies(&url.URL{Scheme: "https", Host: "www.example.co.uk"})
+	expected := []*http.Cookie{
+		{Name: "id", Value: "42"},
+		{Name: "theme", Value: "dark"},
+	}
+
+	assert.Equal(t, expected, actual)
+
+	entries := jar.Entries()
+
+	require.Len(t, entries, 2)
+	assert.Equal(t, "id", entries[0].Name)
+	assert.Equal(t, uint64(0), entries[0].SeqNum)
+	assert.Equal(t, "theme", entries[1].Name)
+	assert.Equal(t, uint64(1), entries[1].SeqNum)
+}
+
+func TestEntry_HTTPCookie(t *testing.T) {
+	t.Parallel()
+
+	expires := time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		scenario string
+		entry    cookiejar.Entry
+		expected *http.Cookie
+	}{
+		{
+			scenario: "host-only session cookie",
+			entry: cookiejar.Entry{
+				Name:     "session",
+				Value:    "abc",
+				Domain:   "www.example.com",
+				Path:     "/",
+				SameSite: "SameSite=Strict",
+				Secure:   true,
+				HttpOnly: true,
+				HostOnly: true,
+				Expires:  time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC),
+			},
+			expected: &http.Cookie{
+				Name:     "session",
+				Value:    "abc",
+				Path:     "/",
+				Secure:   true,
+				HttpOnly: true,
+				SameSite: http.SameSiteStrictMode,
+			},
+		},
+		{
+			scenario: "persistent domain cookie",
+			entry: cookiejar.Entry{
+				Name:        "theme",
+				Value:       "dark mode",
+				Quoted:      true,
+				Domain:      "example.com",
+				Path:        "/app",
+				SameSite:    "SameSite=Lax",
+				Persistent:  true,
+				Partitioned: true,
+				Expires:     expires,
+			},
+			expected: &http.Cookie{
+				Name:        "theme",
+				Value:       "dark mode",
+				Quoted:      true,
+				Domain:      "example.com",
+				Path:        "/app",
+				Expires:     expires,
+				SameSite:    http.SameSiteLaxMode,
+				Partitioned: true,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, tc.entry.HTTPCookie())
+		})
+	}
+}
+
+func TestJar_ExportSetCookies(t *testing.T) {
+	t.Parallel()
+
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+
+	jar.SetCookieHeaders(&url.URL{Scheme: "https", Host: "www.example.com"},
+		"session=abc; Path=/; Secure; HttpOnly; SameSite=Strict",
+		"theme=dark; Domain=example.com; Path=/app; Expires=Fri, 01 Jan 2100 00:00:00 GMT",
+	)
+	jar.SetCookieHeaders(&url.URL{Scheme: "https", Host: "example.org"}, "id=42")
+
+	var lines []string
+
+	for _, c := range jar.ExportSetCookies(".Example.com") {
+		lines = append(lines, c.String())
+	}
+
+	expected := []string{
+		"session=abc; Path=/; HttpOnly; Secure; SameSite=Strict",
+		"theme=dark; Path=/app; Domain=example.com; Expires=Fri, 01 Jan 2100 00:00:00 GMT",
+	}
+
+	assert.Equal(t, expected, lines)
+	assert.Len(t, jar.ExportSetCookies(""), 3)
+	assert.Empty(t, jar.ExportSetCookies("example.net"))
+}
@@ -0,0 +1,2 @@
+// Package firefox imports cookies from the cookies.sqlite database of a Firefox profile.
+package firefox
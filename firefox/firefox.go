@@ -0,0 +1,150 @@
+package firefox
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.nhat.io/cookiejar"
+
+	_ "modernc.org/sqlite" // Pure-Go SQLite driver.
+)
+
+// Values of the sameSite column of moz_cookies.
+const (
+	sameSiteLax    = 1
+	sameSiteStrict = 2
+)
+
+const query = `SELECT name, value, host, path, expiry, lastAccessed, creationTime, isSecure, isHttpOnly, sameSite, originAttributes
+FROM moz_cookies
+ORDER BY creationTime, id`
+
+// Importer is a cookie jar that can import entries, e.g. [cookiejar.Jar] and [cookiejar.PersistentJar].
+type Importer interface {
+	ImportEntries(entries ...cookiejar.Entry)
+}
+
+// Import reads the cookies from the cookies.sqlite database at path and imports them to the jar.
+func Import(ctx context.Context, jar Importer, path string) error {
+	entries, err := ReadEntries(ctx, path)
+	if err != nil {
+		return err
+	}
+
+	jar.ImportEntries(entries...)
+
+	return nil
+}
+
+// ReadEntries reads the cookies from the moz_cookies table of the cookies.sqlite database at path, ordered by their
+// creation time. The database is opened read-only, so it is safe to read it while Firefox is running as long as the
+// database is not locked exclusively.
+func ReadEntries(ctx context.Context, path string) ([]cookiejar.Entry, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open firefox cookies database: %w", err)
+	}
+
+	db, err := sql.Open("sqlite", (&url.URL{Scheme: "file", Path: filepath.ToSlash(path), RawQuery: "mode=ro"}).String())
+	if err != nil {
+		return nil, fmt.Errorf("could not open firefox cookies database: %w", err)
+	}
+
+	defer db.Close() //nolint: errcheck
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("could not query firefox cookies: %w", err)
+	}
+
+	defer rows.Close() //nolint: errcheck
+
+	var entries []cookiejar.Entry
+
+	for rows.Next() {
+		var (
+			name, value, host, cookiePath, originAttributes string
+			expiry, lastAccessed, creationTime              int64
+			isSecure, isHTTPOnly                            bool
+			sameSite                                        int
+		)
+
+		if err := rows.Scan(&name, &value, &host, &cookiePath, &expiry, &lastAccessed, &creationTime,
+			&isSecure, &isHTTPOnly, &sameSite, &originAttributes,
+		); err != nil {
+			return nil, fmt.Errorf("could not read firefox cookie: %w", err)
+		}
+
+		partitionKey := parsePartitionKey(originAttributes)
+
+		entries = append(entries, cookiejar.Entry{
+			Name:         name,
+			Value:        value,
+			Domain:       strings.ToLower(strings.TrimPrefix(host, ".")),
+			Path:         cookiePath,
+			SameSite:     mapSameSite(sameSite),
+			Secure:       isSecure,
+			HttpOnly:     isHTTPOnly,
+			Persistent:   true,
+			HostOnly:     !strings.HasPrefix(host, "."),
+			Partitioned:  partitionKey != "",
+			PartitionKey: partitionKey,
+			Expires:      time.Unix(expiry, 0).UTC(),
+			Creation:     time.UnixMicro(creationTime).UTC(),
+			LastAccess:   time.UnixMicro(lastAccessed).UTC(),
+			SeqNum:       uint64(len(entries)),
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not read firefox cookies: %w", err)
+	}
+
+	return entries, nil
+}
+
+func mapSameSite(sameSite int) string {
+	switch sameSite {
+	case sameSiteLax:
+		return "SameSite=Lax"
+
+	case sameSiteStrict:
+		return "SameSite=Strict"
+
+	default:
+		return ""
+	}
+}
+
+// parsePartitionKey returns the top-level site from the partitionKey of the origin attributes, e.g.
+// "^partitionKey=%28https%2Cexample.com%29" is "https://example.com".
+func parsePartitionKey(originAttributes string) string {
+	attrs, err := url.ParseQuery(strings.TrimPrefix(originAttributes, "^"))
+	if err != nil {
+		return ""
+	}
+
+	key := strings.TrimSuffix(strings.TrimPrefix(attrs.Get("partitionKey"), "("), ")")
+	if key == "" {
+		return ""
+	}
+
+	// The key is (scheme,host) or (scheme,host,port), followed by ",f" for cross-site ancestors in newer versions.
+	parts := strings.Split(key, ",")
+	if len(parts) < 2 {
+		return ""
+	}
+
+	site := parts[0] + "://" + parts[1]
+
+	if len(parts) > 2 && parts[2] != "f" {
+		site += ":" + parts[2]
+	}
+
+	return site
+}
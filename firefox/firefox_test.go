@@ -0,0 +1,109 @@
+package firefox_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.nhat.io/cookiejar"
+	"go.nhat.io/cookiejar/firefox"
+)
+
+func TestReadEntries(t *testing.T) {
+	t.Parallel()
+
+	actual, err := firefox.ReadEntries(context.Background(), "testdata/cookies.sqlite")
+	require.NoError(t, err)
+
+	expires := time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+	expected := []cookiejar.Entry{
+		{
+			Name:       "expired",
+			Value:      "old",
+			Domain:     "www.example.com",
+			Path:       "/",
+			Persistent: true,
+			HostOnly:   true,
+			Expires:    time.Unix(1000000000, 0).UTC(),
+			Creation:   time.Unix(1000000000, 0).UTC(),
+			LastAccess: time.Unix(1000000000, 0).UTC(),
+			SeqNum:     0,
+		},
+		{
+			Name:       "session",
+			Value:      "abc",
+			Domain:     "www.example.com",
+			Path:       "/",
+			SameSite:   "SameSite=Strict",
+			Secure:     true,
+			HttpOnly:   true,
+			Persistent: true,
+			HostOnly:   true,
+			Expires:    expires,
+			Creation:   time.Unix(1700000000, 0).UTC(),
+			LastAccess: time.Unix(1700000001, 0).UTC(),
+			SeqNum:     1,
+		},
+		{
+			Name:       "theme",
+			Value:      "dark",
+			Domain:     "example.com",
+			Path:       "/app",
+			SameSite:   "SameSite=Lax",
+			Persistent: true,
+			Expires:    expires,
+			Creation:   time.Unix(1700000002, 0).UTC(),
+			LastAccess: time.Unix(1700000003, 0).UTC(),
+			SeqNum:     2,
+		},
+		{
+			Name:         "embed",
+			Value:        "1",
+			Domain:       "widget.example.net",
+			Path:         "/",
+			Secure:       true,
+			Persistent:   true,
+			HostOnly:     true,
+			Partitioned:  true,
+			PartitionKey: "https://example.org",
+			Expires:      expires,
+			Creation:     time.Unix(1700000004, 0).UTC(),
+			LastAccess:   time.Unix(1700000005, 0).UTC(),
+			SeqNum:       3,
+		},
+	}
+
+	assert.Equal(t, expected, actual)
+}
+
+func TestReadEntries_Error(t *testing.T) {
+	t.Parallel()
+
+	actual, err := firefox.ReadEntries(context.Background(), "testdata/missing.sqlite")
+
+	assert.Nil(t, actual)
+	assert.ErrorContains(t, err, "could not query firefox cookies")
+}
+
+func TestImport(t *testing.T) {
+	t.Parallel()
+
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+
+	err = firefox.Import(context.Background(), jar, "testdata/cookies.sqlite")
+	require.NoError(t, err)
+
+	actual := jar.Cookies(&url.URL{Scheme: "https", Host: "www.example.com", Path: "/app/"})
+	expected := []*http.Cookie{
+		{Name: "theme", Value: "dark"},
+		{Name: "session", Value: "abc"},
+	}
+
+	assert.Equal(t, expected, actual)
+}
@@ -0,0 +1,31 @@
+package cookiejar
+
+import (
+	"encoding/gob"
+	"io"
+)
+
+var _ EntrySerDer = (*gobSerDer)(nil)
+
+// gobSerDer is a serializer and deserializer for encoding/gob.
+type gobSerDer struct{}
+
+// NewGobSerDer returns a serializer/deserializer for encoding/gob. It is faster than JSON but the files can only be read
+// by Go programs.
+func NewGobSerDer() EntrySerDer {
+	return gobSerDer{}
+}
+
+func (gobSerDer) Serialize(w io.Writer, entries map[string]map[string]Entry) error {
+	return gob.NewEncoder(w).Encode(entries)
+}
+
+func (gobSerDer) Deserialize(r io.Reader) (map[string]map[string]Entry, error) {
+	var entries map[string]map[string]Entry
+
+	if err := gob.NewDecoder(r).Decode(&entries); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
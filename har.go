@@ -0,0 +1,185 @@
+package cookiejar
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// HARCookie is a cookie object of HAR 1.2.
+type HARCookie struct {
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Path     string `json:"path,omitempty"`
+	Domain   string `json:"domain,omitempty"`
+	Expires  string `json:"expires,omitempty"`
+	HTTPOnly bool   `json:"httpOnly"`
+	Secure   bool   `json:"secure"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+// harFile is the part of a HAR 1.2 file that is needed for replaying the cookies.
+type harFile struct {
+	Log struct {
+		Entries []struct {
+			StartedDateTime string `json:"startedDateTime"`
+			Request         struct {
+				URL string `json:"url"`
+			} `json:"request"`
+			Response struct {
+				Headers []struct {
+					Name  string `json:"name"`
+					Value string `json:"value"`
+				} `json:"headers"`
+			} `json:"response"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+// SetCookiesFromHAR replays the Set-Cookie headers of every response of a HAR 1.2 file, in the order of the entries
+// and at their startedDateTime, so that the jar ends up in the state the browser had when the HAR was recorded.
+func (j *Jar) SetCookiesFromHAR(r io.Reader) error {
+	var har harFile
+
+	if err := json.NewDecoder(r).Decode(&har); err != nil {
+		return fmt.Errorf("could not decode har: %w", err)
+	}
+
+	type response struct {
+		url       *url.URL
+		cookies   []*http.Cookie
+		startedAt time.Time
+	}
+
+	// Validate every entry before touching the jar, so that a broken file does not leave it half-replayed.
+	responses := make([]response, len(har.Log.Entries))
+
+	for i, e := range har.Log.Entries {
+		startedAt, err := time.Parse(time.RFC3339Nano, e.StartedDateTime)
+		if err != nil {
+			return fmt.Errorf("invalid startedDateTime of har entry %d: %w", i, err)
+		}
+
+		u, err := url.Parse(e.Request.URL)
+		if err != nil {
+			return fmt.Errorf("invalid request url of har entry %d: %w", i, err)
+		}
+
+		header := make(http.Header)
+
+		for _, h := range e.Response.Headers {
+			if strings.EqualFold(h.Name, "Set-Cookie") {
+				header.Add("Set-Cookie", h.Value)
+			}
+		}
+
+		responses[i] = response{
+			url:       u,
+			cookies:   (&http.Response{Header: header}).Cookies(),
+			startedAt: startedAt,
+		}
+	}
+
+	for _, r := range responses {
+		j.setCookies(r.url, r.cookies, r.startedAt)
+	}
+
+	return nil
+}
+
+// HARCookies returns the cookies that would be sent to u as HAR 1.2 cookie objects. Unlike Cookies, the attributes of
+// the cookies are kept and the last access time of the cookies is not updated.
+func (j *Jar) HARCookies(u *url.URL) []HARCookie {
+	return j.harCookies(u, time.Now())
+}
+
+// harCookies is like HARCookies but takes the current time as a parameter.
+func (j *Jar) harCookies(u *url.URL, now time.Time) []HARCookie {
+	entries := j.sendableEntries(u, now)
+	cookies := make([]HARCookie, 0, len(entries))
+
+	for _, e := range entries {
+		c := HARCookie{
+			Name:     e.Name,
+			Value:    j.sealer.redact(exportEntry(e)).Value,
+			Path:     e.Path,
+			HTTPOnly: e.HttpOnly,
+			Secure:   e.Secure,
+		}
+
+		if !e.HostOnly {
+			c.Domain = "." + e.Domain
+		}
+
+		if e.Persistent {
+			c.Expires = e.Expires.UTC().Format(time.RFC3339Nano)
+		}
+
+		cookies = append(cookies, c)
+	}
+
+	return cookies
+}
+
+// sendableEntries returns the unexpired entries that qualify to be sent to u, in the order of Cookies. Unlike cookies,
+// it does not modify the jar.
+func (j *Jar) sendableEntries(u *url.URL, now time.Time) []entry {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil
+	}
+
+	host, err := canonicalHost(u.Host)
+	if err != nil {
+		return nil
+	}
+
+	https := u.Scheme == "https"
+	path := u.Path
+
+	if path == "" {
+		path = "/"
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var selected []entry
+
+	for _, e := range j.submap(jarKey(host, j.psList)) {
+		if e.Persistent && !e.Expires.After(now) {
+			continue
+		}
+
+		if e.shouldSend(https, host, path) {
+			selected = append(selected, e)
+		}
+	}
+
+	sortSendable(selected)
+
+	return selected
+}
+
+// SetCookiesFromHAR replays the Set-Cookie headers of a HAR 1.2 file, see [Jar.SetCookiesFromHAR].
+func (j *PersistentJar) SetCookiesFromHAR(r io.Reader) error {
+	j.lazyLoad.Do(j.load)
+
+	if err := j.jar.SetCookiesFromHAR(r); err != nil {
+		return err
+	}
+
+	j.syncIfAuto()
+
+	return nil
+}
+
+// HARCookies returns the cookies that would be sent to u as HAR 1.2 cookie objects, see [Jar.HARCookies].
+func (j *PersistentJar) HARCookies(u *url.URL) []HARCookie {
+	j.lazyLoad.Do(j.load)
+
+	return j.jar.HARCookies(u)
+}
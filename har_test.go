@@ -0,0 +1,126 @@
+package cookiejar_test
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.nhat.io/cookiejar"
+)
+
+func TestJar_SetCookiesFromHAR(t *testing.T) {
+	t.Parallel()
+
+	f, err := os.Open("testdata/example.har")
+	require.NoError(t, err)
+
+	defer f.Close() //nolint: errcheck
+
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+
+	err = jar.SetCookiesFromHAR(f)
+	require.NoError(t, err)
+
+	entries := jar.Entries()
+
+	require.Len(t, entries, 3)
+	assert.Equal(t, "flash", entries[1].Name)
+	assert.Equal(t, time.Date(2024, 5, 1, 10, 1, 0, 0, time.UTC), entries[1].Expires.UTC())
+	assert.Equal(t, "theme", entries[2].Name)
+	assert.Equal(t, time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), entries[2].Creation.UTC())
+	assert.Equal(t, time.Date(2024, 5, 1, 10, 0, 1, 500000000, time.UTC), entries[2].LastAccess.UTC())
+
+	// The flash cookie expired a minute after it was recorded.
+	actual := jar.Cookies(&url.URL{Scheme: "https", Host: "www.example.com", Path: "/app/"})
+	expected := []*http.Cookie{
+		{Name: "theme", Value: "dark"},
+		{Name: "session", Value: "abc"},
+	}
+
+	assert.Equal(t, expected, actual)
+}
+
+func TestJar_SetCookiesFromHAR_Error(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario      string
+		har           string
+		expectedError string
+	}{
+		{
+			scenario:      "invalid json",
+			har:           `{`,
+			expectedError: "could not decode har: unexpected EOF",
+		},
+		{
+			scenario: "invalid started date time",
+			har: `{"log": {"entries": [
+				{"startedDateTime": "2024-05-01T10:00:00Z", "request": {"url": "https://example.com/"}, "response": {"headers": [{"name": "Set-Cookie", "value": "id=42"}]}},
+				{"startedDateTime": "yesterday", "request": {"url": "https://example.com/"}}
+			]}}`,
+			expectedError: `invalid startedDateTime of har entry 1: parsing time "yesterday" as "2006-01-02T15:04:05.999999999Z07:00": cannot parse "yesterday" as "2006"`,
+		},
+		{
+			scenario:      "invalid url",
+			har:           `{"log": {"entries": [{"startedDateTime": "2024-05-01T10:00:00Z", "request": {"url": "://"}}]}}`,
+			expectedError: `invalid request url of har entry 0: parse "://": missing protocol scheme`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			jar, err := cookiejar.New(nil)
+			require.NoError(t, err)
+
+			err = jar.SetCookiesFromHAR(strings.NewReader(tc.har))
+
+			assert.EqualError(t, err, tc.expectedError)
+			assert.Empty(t, jar.Entries())
+		})
+	}
+}
+
+func TestJar_HARCookies(t *testing.T) {
+	t.Parallel()
+
+	f, err := os.Open("testdata/example.har")
+	require.NoError(t, err)
+
+	defer f.Close() //nolint: errcheck
+
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+
+	require.NoError(t, jar.SetCookiesFromHAR(f))
+
+	actual := jar.HARCookies(&url.URL{Scheme: "https", Host: "www.example.com", Path: "/app/"})
+	expected := []cookiejar.HARCookie{
+		{
+			Name:    "theme",
+			Value:   "dark",
+			Path:    "/app",
+			Domain:  ".example.com",
+			Expires: "2100-01-01T00:00:00Z",
+		},
+		{
+			Name:     "session",
+			Value:    "abc",
+			Path:     "/",
+			HTTPOnly: true,
+			Secure:   true,
+		},
+	}
+
+	assert.Equal(t, expected, actual)
+	assert.Empty(t, jar.HARCookies(&url.URL{Scheme: "http", Host: "www.example.com", Path: "/"}))
+}
@@ -0,0 +1,76 @@
+package cookiejar
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// SetCookieHeaders parses raw Set-Cookie header values, with or without the "Set-Cookie:" name, the same way as
+// [http.Response.Cookies] and sets them as if they were received from u.
+func (j *Jar) SetCookieHeaders(u *url.URL, headers ...string) {
+	header := make(http.Header)
+
+	for _, h := range headers {
+		header.Add("Set-Cookie", trimHeaderName(h, "Set-Cookie"))
+	}
+
+	j.SetCookies(u, (&http.Response{Header: header}).Cookies())
+}
+
+// ImportCookieHeader parses a raw Cookie request header, with or without the "Cookie:" name, the same way as
+// [http.Request.Cookies] and stores the cookies as host-only session cookies of u. The header does not tell the path
+// of the cookies, so they are stored for "/".
+func (j *Jar) ImportCookieHeader(u *url.URL, header string) {
+	parsed := (&http.Request{Header: http.Header{"Cookie": {trimHeaderName(header, "Cookie")}}}).Cookies()
+	cookies := make([]*http.Cookie, len(parsed))
+
+	for i, c := range parsed {
+		cookies[i] = &http.Cookie{Name: c.Name, Value: c.Value, Quoted: c.Quoted, Path: "/"}
+	}
+
+	j.SetCookies(u, cookies)
+}
+
+// CookieHeader returns the value of the Cookie header that net/http sends with a request to u.
+func (j *Jar) CookieHeader(u *url.URL) string {
+	req := &http.Request{Header: make(http.Header)}
+
+	for _, c := range j.Cookies(u) {
+		req.AddCookie(c)
+	}
+
+	return req.Header.Get("Cookie")
+}
+
+// trimHeaderName removes the optional "name:" in front of a raw header value.
+func trimHeaderName(header, name string) string {
+	header = strings.TrimSpace(header)
+
+	if len(header) > len(name) && header[len(name)] == ':' && strings.EqualFold(header[:len(name)], name) {
+		return strings.TrimSpace(header[len(name)+1:])
+	}
+
+	return header
+}
+
+// SetCookieHeaders parses raw Set-Cookie header values, see [Jar.SetCookieHeaders].
+func (j *PersistentJar) SetCookieHeaders(u *url.URL, headers ...string) {
+	j.lazyLoad.Do(j.load)
+	j.jar.SetCookieHeaders(u, headers...)
+	j.syncIfAuto()
+}
+
+// ImportCookieHeader parses a raw Cookie request header, see [Jar.ImportCookieHeader].
+func (j *PersistentJar) ImportCookieHeader(u *url.URL, header string) {
+	j.lazyLoad.Do(j.load)
+	j.jar.ImportCookieHeader(u, header)
+	j.syncIfAuto()
+}
+
+// CookieHeader returns the value of the Cookie header for a request to u, see [Jar.CookieHeader].
+func (j *PersistentJar) CookieHeader(u *url.URL) string {
+	j.lazyLoad.Do(j.load)
+
+	return j.jar.CookieHeader(u)
+}
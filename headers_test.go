@@ -0,0 +1,86 @@
+package cookiejar_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.nhat.io/cookiejar"
+)
+
+func TestJar_SetCookieHeaders(t *testing.T) {
+	t.Parallel()
+
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+
+	u := &url.URL{Scheme: "https", Host: "www.example.com", Path: "/app/login"}
+
+	jar.SetCookieHeaders(u,
+		"Set-Cookie: session=abc; Path=/; Secure; HttpOnly",
+		`theme="dark mode"; Domain=example.com`,
+		"set-cookie:invalid",
+	)
+
+	actual := jar.Cookies(&url.URL{Scheme: "https", Host: "www.example.com", Path: "/app/index"})
+	expected := []*http.Cookie{
+		{Name: "theme", Value: "dark mode", Quoted: true},
+		{Name: "session", Value: "abc"},
+	}
+
+	assert.Equal(t, expected, actual)
+}
+
+func TestJar_ImportCookieHeader(t *testing.T) {
+	t.Parallel()
+
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+
+	jar.ImportCookieHeader(&url.URL{Scheme: "https", Host: "www.example.com", Path: "/app/"}, "Cookie: a=1; b=2")
+
+	entries := jar.Entries()
+
+	require.Len(t, entries, 2)
+
+	for _, e := range entries {
+		assert.Equal(t, "www.example.com", e.Domain)
+		assert.Equal(t, "/", e.Path)
+		assert.True(t, e.HostOnly)
+		assert.False(t, e.Persistent)
+	}
+
+	assert.Empty(t, jar.CookieHeader(&url.URL{Scheme: "https", Host: "example.com"}))
+	assert.Equal(t, "a=1; b=2", jar.CookieHeader(&url.URL{Scheme: "https", Host: "www.example.com"}))
+}
+
+func TestJar_CookieHeader_SameAsNetHTTP(t *testing.T) {
+	t.Parallel()
+
+	var sent string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		sent = r.Header.Get("Cookie")
+	}))
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+
+	jar.SetCookieHeaders(u, `a=1`, `b="x y"`, `c=3; Path=/sub`)
+
+	resp, err := (&http.Client{Jar: jar}).Get(srv.URL) //nolint: noctx
+	require.NoError(t, err)
+
+	_ = resp.Body.Close() //nolint: errcheck
+
+	assert.Equal(t, `a=1; b="x y"`, sent)
+	assert.Equal(t, sent, jar.CookieHeader(u))
+}
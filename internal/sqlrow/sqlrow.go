@@ -0,0 +1,81 @@
+// Package sqlrow reads and writes the entries of the SQL stores, sqlitestore and sqlstore, as rows.
+package sqlrow
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"go.nhat.io/cookiejar"
+)
+
+// TimeFormat is a fixed-width UTC format, so that the times compare as strings in SQL.
+const TimeFormat = "2006-01-02T15:04:05.000000000Z"
+
+// FormatTime formats t with TimeFormat.
+func FormatTime(t time.Time) string {
+	return t.UTC().Format(TimeFormat)
+}
+
+// ParseTime parses s with TimeFormat.
+func ParseTime(s string) (time.Time, error) {
+	return time.Parse(TimeFormat, s)
+}
+
+// Values returns the keys followed by the values of the domain, path, name, value, quoted, same_site, secure,
+// http_only, persistent, host_only, partitioned, partition_key, expires, creation, last_access and seq_num columns of
+// e, in this order.
+func Values(e cookiejar.Entry, keys ...any) []any {
+	return append(keys,
+		e.Domain, e.Path, e.Name, e.Value, e.Quoted, e.SameSite, e.Secure, e.HttpOnly, e.Persistent, e.HostOnly,
+		e.Partitioned, e.PartitionKey, FormatTime(e.Expires), FormatTime(e.Creation), FormatTime(e.LastAccess),
+		int64(e.SeqNum), //nolint: gosec
+	)
+}
+
+// Scan reads the rows of the eTLD+1 followed by the columns of Values, grouped by their eTLD+1. It does not close the
+// rows.
+func Scan(rows *sql.Rows) (map[string]map[string]cookiejar.Entry, error) {
+	entries := make(map[string]map[string]cookiejar.Entry)
+
+	for rows.Next() {
+		var (
+			etld1, expires, creation, lastAccess string
+			seqNum                               int64
+			e                                    cookiejar.Entry
+		)
+
+		if err := rows.Scan(&etld1, &e.Domain, &e.Path, &e.Name, &e.Value, &e.Quoted, &e.SameSite, &e.Secure,
+			&e.HttpOnly, &e.Persistent, &e.HostOnly, &e.Partitioned, &e.PartitionKey, &expires, &creation, &lastAccess,
+			&seqNum,
+		); err != nil {
+			return nil, fmt.Errorf("could not read cookie: %w", err)
+		}
+
+		e.SeqNum = uint64(seqNum) //nolint: gosec
+
+		var err error
+
+		if e.Expires, err = ParseTime(expires); err == nil {
+			if e.Creation, err = ParseTime(creation); err == nil {
+				e.LastAccess, err = ParseTime(lastAccess)
+			}
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("could not read cookie: %w", err)
+		}
+
+		if entries[etld1] == nil {
+			entries[etld1] = make(map[string]cookiejar.Entry)
+		}
+
+		entries[etld1][e.ID()] = e
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not read cookies: %w", err)
+	}
+
+	return entries, nil
+}
@@ -3,12 +3,18 @@
 // license that can be found in the LICENSE file.
 
 // Package cookiejar implements an in-memory RFC 6265-compliant http.CookieJar.
+//
+// The protobuf format of NewProtobufSerDer, proto/cookiejar.proto, is encoded
+// with protowire rather than with the code generated by protoc-gen-go. The
+// generated code would register cookiejar.v1 in the global registry of every
+// program that imports the package, where it conflicts with a copy generated
+// by the program itself, and would need protoc to build. The encoding is
+// checked against the schema by the tests, which compile the .proto file.
 package cookiejar
 
 import (
 	"cmp"
 	"errors"
-	"fmt"
 	"net"
 	"net/http"
 	"net/url"
@@ -57,12 +63,38 @@ type Options struct {
 	// secure: it means that the HTTP server for foo.co.uk can set a cookie
 	// for bar.co.uk.
 	PublicSuffixList PublicSuffixList
+
+	// Store is the storage of the entries. A nil value keeps the entries in
+	// memory.
+	Store Store
+
+	// StoreErrorHandler is called with the errors of the Store, of the
+	// Sealer and of the Audit sink, as the methods of http.CookieJar cannot
+	// return them. A nil value ignores them.
+	StoreErrorHandler func(err error)
+
+	// Sealer seals the values of the sensitive cookies in memory and at
+	// rest. A nil value keeps all the values in plain text.
+	Sealer *Sealer
+
+	// Audit records every change of the cookies. A nil value records
+	// nothing.
+	Audit AuditSink
+
+	// AuditHashKey is the key of the HMAC of the values in the audit
+	// records. A nil value uses a random key for the life of the jar.
+	AuditHashKey []byte
 }
 
 // Jar implements the http.CookieJar interface from the net/http package.
 type Jar struct {
 	psList PublicSuffixList
 
+	store        Store
+	onStoreError func(err error)
+	sealer       *Sealer
+	audit        AuditSink
+
 	// mu locks the remaining fields.
 	mu sync.Mutex
 
@@ -73,6 +105,13 @@ type Jar struct {
 	// nextSeqNum is the next sequence number assigned to a new cookie
 	// created SetCookies.
 	nextSeqNum uint64
+
+	// auditKey is the key of the HMAC of the values in the audit records.
+	auditKey []byte
+
+	// accessed is the last access time of the entries sent by Cookies that
+	// is not written to the Store yet, keyed like entries.
+	accessed map[string]map[string]time.Time
 }
 
 // New returns a new cookie jar. A nil [*Options] is equivalent to a zero
@@ -83,6 +122,11 @@ func New(o *Options) (*Jar, error) {
 	}
 	if o != nil {
 		jar.psList = o.PublicSuffixList
+		jar.store = o.Store
+		jar.onStoreError = o.StoreErrorHandler
+		jar.sealer = o.Sealer
+		jar.audit = o.Audit
+		jar.auditKey = slices.Clone(o.AuditHashKey)
 	}
 	return jar, nil
 }
@@ -106,15 +150,27 @@ type entry struct {
 	Creation   time.Time
 	LastAccess time.Time
 
+	// Partitioned is the Partitioned attribute of CHIPS. PartitionKey is the
+	// top-level site the cookie is partitioned by, e.g. "https://example.com",
+	// when it is known. The jar stores both but does not enforce them as it
+	// does not know the top-level site of a request.
+	Partitioned  bool
+	PartitionKey string
+
 	// seqNum is a sequence number so that Cookies returns cookies in a
 	// deterministic order, even for cookies that have equal Path length and
 	// equal Creation time. This simplifies testing.
 	seqNum uint64
 }
 
-// id returns the domain;path;name triple of e as an id.
+// id returns the domain;path;name triple of e as an id, see Entry.ID.
 func (e *entry) id() string {
-	return fmt.Sprintf("%s;%s;%s", e.Domain, e.Path, e.Name)
+	return entryID(e.Domain, e.Path, e.Name)
+}
+
+// entryID returns the domain;path;name triple as an id.
+func entryID(domain, path, name string) string {
+	return domain + ";" + path + ";" + name
 }
 
 // shouldSend determines whether e's cookie qualifies to be included in a
@@ -175,43 +231,68 @@ func (j *Jar) cookies(u *url.URL, now time.Time) (cookies []*http.Cookie) {
 	j.mu.Lock()
 	defer j.mu.Unlock()
 
-	submap := j.entries[key]
-	if submap == nil {
-		return cookies
-	}
-
 	https := u.Scheme == "https"
 	path := u.Path
 	if path == "" {
 		path = "/"
 	}
 
-	modified := false
+	// Reading the entries first spares the Store a write transaction when no
+	// cookie is removed, the last access times are then written later.
 	var selected []entry
-	for id, e := range submap {
+	expired := false
+	for _, e := range j.submap(key) {
 		if e.Persistent && !e.Expires.After(now) {
-			delete(submap, id)
-			modified = true
-			continue
+			expired = true
+			break
 		}
-		if !e.shouldSend(https, host, path) {
-			continue
+		if e.shouldSend(https, host, path) {
+			selected = append(selected, e)
 		}
-		e.LastAccess = now
-		submap[id] = e
-		selected = append(selected, e)
-		modified = true
-	}
-	if modified {
-		if len(submap) == 0 {
-			delete(j.entries, key)
-		} else {
-			j.entries[key] = submap
+	}
+
+	if j.store != nil && !expired {
+		if len(selected) > 0 {
+			j.markAccessed(key, selected, now)
+		}
+	} else if expired || len(selected) > 0 {
+		j.updateSubmap(key, j.auditSource(u), now, func(submap map[string]entry) bool {
+			selected = selected[:0]
+			modified := false
+			for id, e := range submap {
+				if e.Persistent && !e.Expires.After(now) {
+					delete(submap, id)
+					modified = true
+					continue
+				}
+				if !e.shouldSend(https, host, path) {
+					continue
+				}
+				e.LastAccess = now
+				submap[id] = e
+				selected = append(selected, e)
+				modified = true
+			}
+			return modified
+		})
+	}
+
+	sortSendable(selected)
+	for _, e := range selected {
+		value, err := j.sealer.unseal(e)
+		if err != nil {
+			j.storeError(err)
+			continue
 		}
+		cookies = append(cookies, &http.Cookie{Name: e.Name, Value: value, Quoted: e.Quoted})
 	}
 
-	// sort according to RFC 6265 section 5.4 point 2: by longest
-	// path and then by earliest creation time.
+	return cookies
+}
+
+// sortSendable sorts the entries to send according to RFC 6265 section 5.4
+// point 2: by longest path and then by earliest creation time.
+func sortSendable(selected []entry) {
 	slices.SortFunc(selected, func(a, b entry) int {
 		if r := cmp.Compare(b.Path, a.Path); r != 0 {
 			return r
@@ -221,11 +302,6 @@ func (j *Jar) cookies(u *url.URL, now time.Time) (cookies []*http.Cookie) {
 		}
 		return cmp.Compare(a.seqNum, b.seqNum)
 	})
-	for _, e := range selected {
-		cookies = append(cookies, &http.Cookie{Name: e.Name, Value: e.Value, Quoted: e.Quoted})
-	}
-
-	return cookies
 }
 
 // SetCookies implements the SetCookies method of the [http.CookieJar] interface.
@@ -253,48 +329,42 @@ func (j *Jar) setCookies(u *url.URL, cookies []*http.Cookie, now time.Time) {
 	j.mu.Lock()
 	defer j.mu.Unlock()
 
-	submap := j.entries[key]
-
-	modified := false
-	for _, cookie := range cookies {
-		e, remove, err := j.newEntry(cookie, now, defPath, host)
-		if err != nil {
-			continue
-		}
-		id := e.id()
-		if remove {
-			if submap != nil {
+	j.updateSubmap(key, j.auditSource(u), now, func(submap map[string]entry) bool {
+		modified := false
+		for _, cookie := range cookies {
+			e, remove, err := j.newEntry(cookie, now, defPath, host)
+			if err != nil {
+				continue
+			}
+			id := e.id()
+			if !remove {
+				if err := j.sealer.seal(&e); err != nil {
+					j.storeError(err)
+					continue
+				}
+			}
+			if remove {
 				if _, ok := submap[id]; ok {
 					delete(submap, id)
 					modified = true
 				}
+				continue
 			}
-			continue
-		}
-		if submap == nil {
-			submap = make(map[string]entry)
-		}
-
-		if old, ok := submap[id]; ok {
-			e.Creation = old.Creation
-			e.seqNum = old.seqNum
-		} else {
-			e.Creation = now
-			e.seqNum = j.nextSeqNum
-			j.nextSeqNum++
-		}
-		e.LastAccess = now
-		submap[id] = e
-		modified = true
-	}
 
-	if modified {
-		if len(submap) == 0 {
-			delete(j.entries, key)
-		} else {
-			j.entries[key] = submap
+			if old, ok := submap[id]; ok {
+				e.Creation = old.Creation
+				e.seqNum = old.seqNum
+			} else {
+				e.Creation = now
+				e.seqNum = j.nextSeqNum
+				j.nextSeqNum++
+			}
+			e.LastAccess = now
+			submap[id] = e
+			modified = true
 		}
-	}
+		return modified
+	})
 }
 
 // canonicalHost strips port from host if present and returns the canonicalized
@@ -434,6 +504,7 @@ func (j *Jar) newEntry(c *http.Cookie, now time.Time, defPath, host string) (e e
 	e.Quoted = c.Quoted
 	e.Secure = c.Secure
 	e.HttpOnly = c.HttpOnly
+	e.Partitioned = c.Partitioned
 
 	switch c.SameSite {
 	case http.SameSiteDefaultMode:
@@ -0,0 +1,339 @@
+package cookiejar
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bool64/ctxd"
+	"github.com/spf13/afero"
+)
+
+const (
+	journalPutOp    = "put"
+	journalDeleteOp = "del"
+
+	defaultJournalCompactSize  = 4 << 20
+	defaultJournalCompactRatio = 1
+)
+
+var _ Store = (*journal)(nil)
+
+// journalRecord is a line of the journal.
+type journalRecord struct {
+	Op    string `json:"op"`
+	Key   string `json:"key"`
+	ID    string `json:"id"`
+	Entry *Entry `json:"entry,omitempty"`
+}
+
+// journal is the store of a PersistentJar in journal mode. The entries are kept in memory and every change is appended
+// to the journal file as a JSON line, the file of the PersistentJar is a checkpoint that is only rewritten when the
+// journal is compacted.
+//
+// The journal is used with the lock of the jar held, it does not have its own.
+type journal struct {
+	fs       afero.Fs
+	path     string
+	filePerm os.FileMode
+
+	compactSize  int64
+	compactRatio float64
+
+	entries        map[string]map[string]Entry
+	file           afero.File
+	size           int64
+	checkpointSize int64
+}
+
+func (j *journal) Entries(key string) (map[string]Entry, error) {
+	return maps.Clone(j.entries[key]), nil
+}
+
+func (j *journal) All() (map[string]map[string]Entry, error) {
+	all := make(map[string]map[string]Entry, len(j.entries))
+
+	for key, entries := range j.entries {
+		all[key] = maps.Clone(entries)
+	}
+
+	return all, nil
+}
+
+// Update appends the changes made by fn to the journal. The changes are kept in memory even if they cannot be appended,
+// they are then persisted by the next compaction. A change of the last access time only is not appended, so that
+// reading the cookies does not grow the journal, it is persisted by the next compaction.
+func (j *journal) Update(key string, fn func(entries map[string]Entry) bool) error {
+	before := j.entries[key]
+	after := maps.Clone(before)
+
+	if after == nil {
+		after = make(map[string]Entry)
+	}
+
+	if !fn(after) {
+		return nil
+	}
+
+	if len(after) == 0 {
+		delete(j.entries, key)
+	} else {
+		j.entries[key] = after
+	}
+
+	upserts, deletes := DiffEntries(before, after)
+
+	var buf bytes.Buffer
+
+	enc := json.NewEncoder(&buf)
+
+	for _, e := range deletes {
+		_ = enc.Encode(journalRecord{Op: journalDeleteOp, Key: key, ID: e.ID()}) //nolint: errcheck
+	}
+
+	for _, e := range upserts {
+		if old, ok := before[e.ID()]; ok && onlyAccessed(old, e) {
+			continue
+		}
+
+		_ = enc.Encode(journalRecord{Op: journalPutOp, Key: key, ID: e.ID(), Entry: &e}) //nolint: errcheck
+	}
+
+	return j.append(buf.Bytes())
+}
+
+// append writes the records to the end of the journal, in one write so that a crash tears at most the last line.
+func (j *journal) append(records []byte) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	if j.file == nil {
+		f, err := j.fs.OpenFile(filepath.Clean(j.path), os.O_WRONLY|os.O_APPEND|os.O_CREATE, j.filePerm)
+		if err != nil {
+			return fmt.Errorf("could not open cookies journal: %w", err)
+		}
+
+		j.file = f
+	}
+
+	n, err := j.file.Write(records)
+	j.size += int64(n)
+
+	if err != nil {
+		return fmt.Errorf("could not append to cookies journal: %w", err)
+	}
+
+	return nil
+}
+
+// needsCompaction reports whether the journal is larger than the compaction size or than the checkpoint times the
+// compaction ratio.
+func (j *journal) needsCompaction() bool {
+	if j.size == 0 {
+		return false
+	}
+
+	return j.size >= j.compactSize || float64(j.size) >= j.compactRatio*float64(j.checkpointSize)
+}
+
+// sync flushes the journal to the disk.
+func (j *journal) sync() error {
+	if j.file == nil {
+		return nil
+	}
+
+	if err := j.file.Sync(); err != nil {
+		return fmt.Errorf("could not sync cookies journal: %w", err)
+	}
+
+	return nil
+}
+
+// close closes the journal file. The next append opens it again.
+func (j *journal) close() error {
+	if j.file == nil {
+		return nil
+	}
+
+	err := j.file.Close()
+	j.file = nil
+
+	if err != nil {
+		return fmt.Errorf("could not close cookies journal: %w", err)
+	}
+
+	return nil
+}
+
+// compact writes the entries to a new checkpoint, replaces the old one and then truncates the journal. A crash before
+// the truncation replays the journal over the new checkpoint, which gives the same entries.
+func (j *journal) compact(serder EntrySerDer, checkpointPath string, filePerm os.FileMode) error {
+	tmpPath := filepath.Clean(checkpointPath) + ".tmp"
+
+	f, err := j.fs.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, filePerm)
+	if err != nil {
+		return fmt.Errorf("could not open cookies checkpoint: %w", err)
+	}
+
+	counter := &countingWriter{w: f}
+
+	err = serder.Serialize(counter, j.entries)
+	if err == nil {
+		err = f.Sync()
+	}
+
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		_ = j.fs.Remove(tmpPath) //nolint: errcheck
+
+		return fmt.Errorf("could not write cookies checkpoint: %w", err)
+	}
+
+	if err := j.fs.Rename(tmpPath, filepath.Clean(checkpointPath)); err != nil {
+		return fmt.Errorf("could not replace cookies checkpoint: %w", err)
+	}
+
+	j.checkpointSize = counter.n
+
+	if j.file != nil {
+		_ = j.file.Close() //nolint: errcheck
+
+		j.file = nil
+	}
+
+	if err := j.truncate(0); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("could not truncate cookies journal: %w", err)
+	}
+
+	j.size = 0
+
+	return nil
+}
+
+// replay applies the records of the journal to the entries, which must be keyed with psl. The records are keyed with
+// psl too, not with the list they were written with. A line that cannot be decoded, usually the last one torn by a
+// crash, ends the journal: the journal is truncated before it, so that the next records are appended after the last
+// good one.
+func (j *journal) replay(
+	ctx context.Context,
+	entries map[string]map[string]Entry,
+	psl PublicSuffixList,
+	logger ctxd.Logger,
+) error {
+	data, err := afero.ReadFile(j.fs, filepath.Clean(j.path))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		return fmt.Errorf("could not read cookies journal: %w", err)
+	}
+
+	r := bufio.NewReader(bytes.NewReader(data))
+	offset := int64(0)
+
+	for {
+		line, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) && len(line) == 0 {
+			break
+		}
+
+		var record journalRecord
+
+		if err != nil || json.Unmarshal(line, &record) != nil || !record.valid() {
+			logger.Warn(ctx, "truncated torn cookies journal",
+				"cookies.journal.offset", offset,
+				"cookies.journal.dropped", len(data)-int(offset),
+			)
+
+			if err := j.truncate(offset); err != nil {
+				return fmt.Errorf("could not truncate torn cookies journal: %w", err)
+			}
+
+			break
+		}
+
+		offset += int64(len(line))
+
+		record.apply(entries, psl)
+	}
+
+	j.size = offset
+
+	return nil
+}
+
+// truncate truncates the journal file to size.
+func (j *journal) truncate(size int64) error {
+	f, err := j.fs.OpenFile(filepath.Clean(j.path), os.O_WRONLY, j.filePerm)
+	if err != nil {
+		return err
+	}
+
+	err = f.Truncate(size)
+
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+func (r journalRecord) valid() bool {
+	switch r.Op {
+	case journalPutOp:
+		return r.Entry != nil && r.Entry.ID() == r.ID
+
+	case journalDeleteOp:
+		return r.Entry == nil
+
+	default:
+		return false
+	}
+}
+
+// apply applies the record to the entries keyed with psl.
+func (r journalRecord) apply(entries map[string]map[string]Entry, psl PublicSuffixList) {
+	domain, _, _ := strings.Cut(r.ID, ";")
+	key := jarKey(domain, psl)
+
+	switch r.Op {
+	case journalPutOp:
+		if entries[key] == nil {
+			entries[key] = make(map[string]Entry)
+		}
+
+		entries[key][r.ID] = *r.Entry
+
+	case journalDeleteOp:
+		delete(entries[key], r.ID)
+
+		if len(entries[key]) == 0 {
+			delete(entries, key)
+		}
+	}
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+
+	return n, err
+}
@@ -0,0 +1,208 @@
+package cookiejar_test
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.nhat.io/cookiejar"
+)
+
+func TestPersistentJar_Journal(t *testing.T) {
+	t.Parallel()
+
+	const filePath = "/tmp/cookies.json"
+
+	fs := afero.NewMemMapFs()
+	u := &url.URL{Scheme: "https", Host: "example.com", Path: "/"}
+
+	newJar := func() *cookiejar.PersistentJar {
+		return cookiejar.NewPersistentJar(
+			cookiejar.WithFs(fs),
+			cookiejar.WithFilePath(filePath),
+			cookiejar.WithJournal(1<<20, 10),
+		)
+	}
+
+	j := newJar()
+
+	j.SetCookies(u, []*http.Cookie{{Name: "id", Value: "42"}, {Name: "theme", Value: "dark"}})
+	j.SetCookies(u, []*http.Cookie{{Name: "theme", MaxAge: -1}})
+
+	// The changes are appended to the journal, the checkpoint is not written.
+	journal, err := afero.ReadFile(fs, filePath+".journal")
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(journal)), "\n")
+
+	require.Len(t, lines, 3)
+	assert.Contains(t, lines[0], `"op":"put"`)
+	assert.Contains(t, lines[1], `"op":"put"`)
+	assert.Equal(t, `{"op":"del","key":"example.com","id":"example.com;/;theme"}`, lines[2])
+
+	_, err = fs.Stat(filePath)
+	require.ErrorIs(t, err, afero.ErrFileNotFound)
+
+	// The journal is replayed over the missing checkpoint.
+	assert.Equal(t, []*http.Cookie{{Name: "id", Value: "42"}}, newJar().Cookies(u))
+
+	// The first sync compacts, as there is no checkpoint yet.
+	require.NoError(t, j.Sync())
+
+	journal, err = afero.ReadFile(fs, filePath+".journal")
+	require.NoError(t, err)
+	assert.Empty(t, journal)
+
+	checkpoint, err := afero.ReadFile(fs, filePath)
+	require.NoError(t, err)
+	assert.Contains(t, string(checkpoint), `"example.com;/;id"`)
+
+	j.SetCookies(u, []*http.Cookie{{Name: "lang", Value: "en"}})
+
+	// The small journal is only flushed.
+	require.NoError(t, j.Sync())
+
+	journal, err = afero.ReadFile(fs, filePath+".journal")
+	require.NoError(t, err)
+	assert.NotEmpty(t, journal)
+
+	assert.Equal(t, []*http.Cookie{{Name: "id", Value: "42"}, {Name: "lang", Value: "en"}}, newJar().Cookies(u))
+}
+
+func TestPersistentJar_Journal_Access(t *testing.T) {
+	t.Parallel()
+
+	const filePath = "/tmp/cookies.json"
+
+	fs := afero.NewMemMapFs()
+	u := &url.URL{Scheme: "https", Host: "example.com", Path: "/"}
+
+	j := cookiejar.NewPersistentJar(
+		cookiejar.WithFs(fs),
+		cookiejar.WithFilePath(filePath),
+		cookiejar.WithJournal(1<<20, 10),
+	)
+
+	j.SetCookies(u, []*http.Cookie{{Name: "id", Value: "42"}})
+
+	// The first sync writes the checkpoint, the next change is journaled.
+	require.NoError(t, j.Sync())
+
+	j.SetCookies(u, []*http.Cookie{{Name: "lang", Value: "en"}})
+
+	journal, err := afero.ReadFile(fs, filePath+".journal")
+	require.NoError(t, err)
+	require.NotEmpty(t, journal)
+
+	// Reading the cookies only changes their last access time, which is not appended to the journal.
+	for range 10 {
+		assert.Equal(t, []*http.Cookie{{Name: "id", Value: "42"}, {Name: "lang", Value: "en"}}, j.Cookies(u))
+		require.NoError(t, j.Sync())
+	}
+
+	after, err := afero.ReadFile(fs, filePath+".journal")
+	require.NoError(t, err)
+
+	assert.Len(t, after, len(journal))
+}
+
+func TestPersistentJar_Journal_TornLine(t *testing.T) {
+	t.Parallel()
+
+	const filePath = "/tmp/cookies.json"
+
+	fs := afero.NewMemMapFs()
+	u := &url.URL{Scheme: "https", Host: "example.com", Path: "/"}
+
+	j := cookiejar.NewPersistentJar(cookiejar.WithFs(fs), cookiejar.WithFilePath(filePath), cookiejar.WithJournal(0, 0))
+
+	j.SetCookies(u, []*http.Cookie{{Name: "id", Value: "42"}})
+
+	journal, err := afero.ReadFile(fs, filePath+".journal")
+	require.NoError(t, err)
+
+	// A crash tore the last record.
+	require.NoError(t, afero.WriteFile(fs, filePath+".journal",
+		append(journal, `{"op":"put","key":"example.com","id":"example.com;/;lang","entry":{"Na`...), 0o600))
+
+	j = cookiejar.NewPersistentJar(cookiejar.WithFs(fs), cookiejar.WithFilePath(filePath), cookiejar.WithJournal(0, 0))
+
+	assert.Equal(t, []*http.Cookie{{Name: "id", Value: "42"}}, j.Cookies(u))
+
+	// The torn record is truncated and the next records are appended after the last good one.
+	j.SetCookies(u, []*http.Cookie{{Name: "lang", Value: "en"}})
+
+	j = cookiejar.NewPersistentJar(cookiejar.WithFs(fs), cookiejar.WithFilePath(filePath), cookiejar.WithJournal(0, 0))
+
+	assert.Equal(t, []*http.Cookie{{Name: "id", Value: "42"}, {Name: "lang", Value: "en"}}, j.Cookies(u))
+}
+
+func TestPersistentJar_Journal_Rekey(t *testing.T) {
+	t.Parallel()
+
+	u := &url.URL{Scheme: "https", Host: "www.example.co.uk", Path: "/"}
+
+	testCases := []struct {
+		scenario string
+		serder   cookiejar.EntrySerDer
+		before   cookiejar.PublicSuffixList
+		after    cookiejar.PublicSuffixList
+	}{
+		{
+			// The Netscape format does not store the keys, the checkpoint is keyed without a public suffix list.
+			scenario: "netscape",
+			serder:   cookiejar.NewNetscapeSerDer(),
+			before:   suffixList{"co.uk"},
+			after:    suffixList{"co.uk"},
+		},
+		{
+			scenario: "json after a public suffix list change",
+			after:    suffixList{"co.uk"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			const filePath = "/tmp/cookies"
+
+			fs := afero.NewMemMapFs()
+
+			newJar := func(psl cookiejar.PublicSuffixList) *cookiejar.PersistentJar {
+				opts := []cookiejar.PersistentJarOption{
+					cookiejar.WithFs(fs),
+					cookiejar.WithFilePath(filePath),
+					cookiejar.WithJournal(1<<20, 10),
+					cookiejar.WithPublicSuffixList(psl),
+				}
+
+				if tc.serder != nil {
+					opts = append(opts, cookiejar.WithSerDer(tc.serder))
+				}
+
+				return cookiejar.NewPersistentJar(opts...)
+			}
+
+			j := newJar(tc.before)
+
+			j.SetCookies(u, []*http.Cookie{{Name: "id", Value: "42"}, {Name: "theme", Value: "dark"}})
+
+			// The first sync compacts, as there is no checkpoint yet.
+			require.NoError(t, j.Sync())
+
+			j.SetCookies(u, []*http.Cookie{{Name: "theme", MaxAge: -1}, {Name: "lang", Value: "en"}})
+			require.NoError(t, j.Sync())
+
+			// The deleted cookie does not come back from the checkpoint.
+			expected := []*http.Cookie{{Name: "id", Value: "42"}, {Name: "lang", Value: "en"}}
+
+			assert.Equal(t, expected, newJar(tc.after).Cookies(u))
+		})
+	}
+}
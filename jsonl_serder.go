@@ -0,0 +1,89 @@
+package cookiejar
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"iter"
+)
+
+var _ StreamEntrySerDer = (*jsonLinesSerDer)(nil)
+
+// jsonLinesSerDer is a serializer and deserializer for JSON lines, one entry per line.
+type jsonLinesSerDer struct{}
+
+// NewJSONLinesSerDer returns a serializer/deserializer for JSON lines, with one entry per line in the same JSON as the
+// default serializer.
+//
+// It implements StreamEntrySerDer, so PersistentJar loads and persists a jar of any size with a constant amount of
+// extra memory.
+func NewJSONLinesSerDer() StreamEntrySerDer {
+	return jsonLinesSerDer{}
+}
+
+func (s jsonLinesSerDer) Serialize(w io.Writer, entries map[string]map[string]Entry) error {
+	return s.SerializeSeq(w, func(yield func(Entry) bool) {
+		for _, key := range sortedKeys(entries) {
+			submap := entries[key]
+
+			for _, id := range sortedKeys(submap) {
+				if !yield(submap[id]) {
+					return
+				}
+			}
+		}
+	})
+}
+
+func (s jsonLinesSerDer) Deserialize(r io.Reader) (map[string]map[string]Entry, error) {
+	entries := make(map[string]map[string]Entry)
+
+	for e, err := range s.DeserializeSeq(r) {
+		if err != nil {
+			return nil, err
+		}
+
+		addEntry(entries, e)
+	}
+
+	return entries, nil
+}
+
+func (jsonLinesSerDer) SerializeSeq(w io.Writer, entries iter.Seq[Entry]) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+
+	for e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
+
+func (jsonLinesSerDer) DeserializeSeq(r io.Reader) iter.Seq2[Entry, error] {
+	return func(yield func(Entry, error) bool) {
+		dec := json.NewDecoder(r)
+
+		for {
+			var e Entry
+
+			err := dec.Decode(&e)
+			if errors.Is(err, io.EOF) {
+				return
+			}
+
+			if err != nil {
+				yield(Entry{}, err)
+
+				return
+			}
+
+			if !yield(e, nil) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package cookiejar_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.nhat.io/cookiejar"
+)
+
+func TestJSONLinesSerDer_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	entries := map[string]map[string]cookiejar.Entry{
+		"example.com": {
+			"example.com;/;id": {Name: "id", Value: "42", Domain: "example.com", Path: "/", HostOnly: true, SeqNum: 1},
+			"www.example.com;/;lang": {
+				Name:       "lang",
+				Value:      "en",
+				Domain:     "www.example.com",
+				Path:       "/",
+				Persistent: true,
+				Expires:    time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC),
+			},
+		},
+		"example.org": {
+			"example.org;/;theme": {Name: "theme", Value: "dark", Domain: "example.org", Path: "/"},
+		},
+	}
+
+	s := cookiejar.NewJSONLinesSerDer()
+
+	var buf bytes.Buffer
+
+	require.NoError(t, s.Serialize(&buf, entries))
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+
+	require.Len(t, lines, 3)
+	assert.Contains(t, lines[0], `"Name":"id"`)
+	assert.Contains(t, lines[1], `"Name":"lang"`)
+	assert.Contains(t, lines[2], `"Name":"theme"`)
+
+	actual, err := s.Deserialize(&buf)
+	require.NoError(t, err)
+
+	assert.Equal(t, entries, actual)
+}
+
+func TestJSONLinesSerDer_DeserializeSeq(t *testing.T) {
+	t.Parallel()
+
+	data := `{"Name":"id","Domain":"example.com","Path":"/"}
+{"Name":"lang","Domain":"example.com","Path":"/"}
+{"Name":
+`
+
+	var names []string
+
+	var lastErr error
+
+	for e, err := range cookiejar.NewJSONLinesSerDer().DeserializeSeq(strings.NewReader(data)) {
+		if err != nil {
+			lastErr = err
+
+			break
+		}
+
+		names = append(names, e.Name)
+	}
+
+	assert.Equal(t, []string{"id", "lang"}, names)
+	require.Error(t, lastErr)
+
+	actual, err := cookiejar.NewJSONLinesSerDer().Deserialize(strings.NewReader(data))
+	require.Error(t, err)
+	assert.Nil(t, actual)
+}
+
+func TestPersistentJar_StreamEntrySerDer(t *testing.T) {
+	t.Parallel()
+
+	const filePath = "/tmp/cookies.jsonl"
+
+	fs := afero.NewMemMapFs()
+	u := &url.URL{Scheme: "https", Host: "www.example.com", Path: "/"}
+
+	newJar := func() *cookiejar.PersistentJar {
+		return cookiejar.NewPersistentJar(
+			cookiejar.WithFs(fs),
+			cookiejar.WithFilePath(filePath),
+			cookiejar.WithSerDer(cookiejar.NewJSONLinesSerDer()),
+		)
+	}
+
+	j := newJar()
+
+	j.SetCookies(u, []*http.Cookie{{Name: "id", Value: "42"}, {Name: "lang", Value: "en", Domain: "example.com"}})
+	require.NoError(t, j.Sync())
+
+	data, err := afero.ReadFile(fs, filePath)
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, bytes.Count(data, []byte("\n")))
+
+	reopened := newJar()
+
+	assert.Equal(t, []*http.Cookie{{Name: "id", Value: "42"}, {Name: "lang", Value: "en"}}, reopened.Cookies(u))
+
+	// A new cookie sorts after the loaded ones.
+	reopened.SetCookies(u, []*http.Cookie{{Name: "theme", Value: "dark"}})
+
+	expected := []*http.Cookie{{Name: "id", Value: "42"}, {Name: "lang", Value: "en"}, {Name: "theme", Value: "dark"}}
+
+	assert.Equal(t, expected, reopened.Cookies(u))
+
+	// A broken file is not loaded.
+	require.NoError(t, afero.WriteFile(fs, filePath, append(data, "{"...), 0o600))
+
+	assert.Empty(t, newJar().Cookies(u))
+}
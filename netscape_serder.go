@@ -0,0 +1,162 @@
+package cookiejar
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	netscapeHeader         = "# Netscape HTTP Cookie File"
+	netscapeHTTPOnlyPrefix = "#HttpOnly_"
+	netscapeFields         = 7
+)
+
+var _ EntrySerDer = (*netscapeSerDer)(nil)
+
+// netscapeSerDer is a serializer and deserializer for the Netscape cookies.txt format used by curl, wget and yt-dlp.
+//
+// Each line has 7 tab-separated fields: domain, include subdomains, path, secure, expiry, name and value. A domain
+// prefixed with "#HttpOnly_" marks an HttpOnly cookie and an expiry of 0 marks a session cookie. The format does not
+// have SameSite, creation and last access time.
+type netscapeSerDer struct{}
+
+// NewNetscapeSerDer returns a serializer/deserializer for the Netscape cookies.txt format.
+//
+// The format does not store the eTLD+1 keys, see [EntrySerDer].
+func NewNetscapeSerDer() EntrySerDer {
+	return netscapeSerDer{}
+}
+
+func (netscapeSerDer) Serialize(w io.Writer, entries map[string]map[string]Entry) error {
+	bw := bufio.NewWriter(w)
+
+	if _, err := fmt.Fprintf(bw, "%s\n\n", netscapeHeader); err != nil {
+		return err
+	}
+
+	for _, e := range sortedEntries(entries) {
+		domain := e.Domain
+		if !e.HostOnly {
+			domain = "." + domain
+		}
+
+		if e.HttpOnly {
+			domain = netscapeHTTPOnlyPrefix + domain
+		}
+
+		var expires int64
+		if e.Persistent {
+			expires = e.Expires.Unix()
+		}
+
+		if _, err := fmt.Fprintf(bw, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			domain, netscapeBool(!e.HostOnly), e.Path, netscapeBool(e.Secure), expires, e.Name, e.Value,
+		); err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
+
+func (netscapeSerDer) Deserialize(r io.Reader) (map[string]map[string]Entry, error) {
+	entries := make(map[string]map[string]Entry)
+	scanner := bufio.NewScanner(r)
+	seqNum := uint64(0)
+
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		httpOnly := strings.HasPrefix(line, netscapeHTTPOnlyPrefix)
+		if httpOnly {
+			line = strings.TrimPrefix(line, netscapeHTTPOnlyPrefix)
+		} else if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.SplitN(line, "\t", netscapeFields)
+		if len(fields) != netscapeFields {
+			return nil, fmt.Errorf("cookiejar: malformed cookies.txt line %d: expected %d fields, got %d", lineNum, netscapeFields, len(fields))
+		}
+
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("cookiejar: malformed cookies.txt line %d: invalid expiry: %w", lineNum, err)
+		}
+
+		e := Entry{
+			Name:     fields[5],
+			Value:    fields[6],
+			Domain:   strings.ToLower(strings.TrimPrefix(fields[0], ".")),
+			Path:     fields[2],
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+			HttpOnly: httpOnly,
+			HostOnly: !strings.EqualFold(fields[1], "TRUE"),
+			Expires:  endOfTime,
+			SeqNum:   seqNum,
+		}
+
+		if expires != 0 {
+			e.Expires = time.Unix(expires, 0).UTC()
+			e.Persistent = true
+		}
+
+		addEntry(entries, e)
+
+		seqNum++
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func netscapeBool(b bool) string {
+	if b {
+		return "TRUE"
+	}
+
+	return "FALSE"
+}
+
+// addEntry adds e to entries under its jar key computed without a public suffix list.
+func addEntry(entries map[string]map[string]Entry, e Entry) {
+	key := jarKey(e.Domain, nil)
+	id := e.ID()
+
+	if entries[key] == nil {
+		entries[key] = make(map[string]Entry)
+	}
+
+	entries[key][id] = e
+}
+
+// sortedEntries flattens entries in a deterministic order: by sequence number, then by domain, path and name.
+func sortedEntries(entries map[string]map[string]Entry) []Entry {
+	var result []Entry
+
+	for _, submap := range entries {
+		for _, e := range submap {
+			result = append(result, e)
+		}
+	}
+
+	slices.SortFunc(result, func(a, b Entry) int {
+		return cmp.Or(
+			cmp.Compare(a.SeqNum, b.SeqNum),
+			cmp.Compare(a.Domain, b.Domain),
+			cmp.Compare(a.Path, b.Path),
+			cmp.Compare(a.Name, b.Name),
+		)
+	})
+
+	return result
+}
@@ -0,0 +1,138 @@
+package cookiejar_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.nhat.io/cookiejar"
+)
+
+const curlCookiesTxt = `# Netscape HTTP Cookie File
+# https://curl.se/docs/http-cookies.html
+# This file was generated by libcurl! Edit at your own risk.
+
+#HttpOnly_example.com	FALSE	/	TRUE	0	session	abc
+.example.com	TRUE	/app	FALSE	4102444800	theme	dark	mode
+`
+
+func TestNetscapeSerDer_Deserialize(t *testing.T) {
+	t.Parallel()
+
+	actual, err := cookiejar.NewNetscapeSerDer().Deserialize(strings.NewReader(curlCookiesTxt))
+	require.NoError(t, err)
+
+	expected := map[string]map[string]cookiejar.Entry{
+		"example.com": {
+			"example.com;/;session": {
+				Name:     "session",
+				Value:    "abc",
+				Domain:   "example.com",
+				Path:     "/",
+				Secure:   true,
+				HttpOnly: true,
+				HostOnly: true,
+				Expires:  time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC),
+				SeqNum:   0,
+			},
+			"example.com;/app;theme": {
+				Name:       "theme",
+				Value:      "dark\tmode",
+				Domain:     "example.com",
+				Path:       "/app",
+				Persistent: true,
+				Expires:    time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC),
+				SeqNum:     1,
+			},
+		},
+	}
+
+	assert.Equal(t, expected, actual)
+}
+
+func TestNetscapeSerDer_Deserialize_Error(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario      string
+		content       string
+		expectedError string
+	}{
+		{
+			scenario:      "missing fields",
+			content:       "# comment\nexample.com\tFALSE\t/\n",
+			expectedError: "cookiejar: malformed cookies.txt line 2: expected 7 fields, got 3",
+		},
+		{
+			scenario:      "invalid expiry",
+			content:       "example.com\tFALSE\t/\tFALSE\tsoon\tid\t42\n",
+			expectedError: `cookiejar: malformed cookies.txt line 1: invalid expiry: strconv.ParseInt: parsing "soon": invalid syntax`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := cookiejar.NewNetscapeSerDer().Deserialize(strings.NewReader(tc.content))
+
+			assert.Nil(t, actual)
+			assert.EqualError(t, err, tc.expectedError)
+		})
+	}
+}
+
+func TestNetscapeSerDer_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	s := cookiejar.NewNetscapeSerDer()
+
+	entries, err := s.Deserialize(strings.NewReader(curlCookiesTxt))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+
+	err = s.Serialize(&buf, entries)
+	require.NoError(t, err)
+
+	expected := `# Netscape HTTP Cookie File
+
+#HttpOnly_example.com	FALSE	/	TRUE	0	session	abc
+.example.com	TRUE	/app	FALSE	4102444800	theme	dark	mode
+`
+
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestPersistentJar_NetscapeSerDer(t *testing.T) {
+	t.Parallel()
+
+	const filePath = "/tmp/cookies.txt"
+
+	fs := afero.NewMemMapFs()
+
+	require.NoError(t, afero.WriteFile(fs, filePath, []byte(curlCookiesTxt), 0o600))
+
+	j := cookiejar.NewPersistentJar(
+		cookiejar.WithFs(fs),
+		cookiejar.WithFilePath(filePath),
+		cookiejar.WithSerDer(cookiejar.NewNetscapeSerDer()),
+	)
+
+	actual := j.Cookies(&url.URL{Scheme: "https", Host: "www.example.com", Path: "/app/index.html"})
+	expected := []*http.Cookie{{Name: "theme", Value: "dark\tmode"}}
+
+	assert.Equal(t, expected, actual)
+
+	actual = j.Cookies(&url.URL{Scheme: "https", Host: "example.com", Path: "/"})
+	expected = []*http.Cookie{{Name: "session", Value: "abc"}}
+
+	assert.Equal(t, expected, actual)
+}
@@ -5,10 +5,12 @@ import (
 	"encoding/json"
 	"errors"
 	"io"
+	"iter"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"sync"
 	"time"
 
@@ -34,6 +36,12 @@ type PersistentJar struct {
 	filePerm os.FileMode
 
 	lazyLoad sync.Once
+	// loadErr is the error that prevented the file from being loaded, Sync does not overwrite the file then. It is
+	// locked by j.jar.mu.
+	loadErr error
+	onRekey func(report RekeyReport)
+	journal *journal
+	sites   *siteFiles
 }
 
 // SetCookies implements the SetCookies method of the http.CookieJar interface.
@@ -42,11 +50,17 @@ type PersistentJar struct {
 func (j *PersistentJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
 	j.lazyLoad.Do(j.load)
 	j.jar.SetCookies(u, cookies)
+	j.syncIfAuto()
+}
 
-	if j.autoSync {
-		if err := j.Sync(); err != nil {
-			j.logger.Error(context.Background(), err.Error())
-		}
+// syncIfAuto persists cookies to the file if the auto sync mode is on.
+func (j *PersistentJar) syncIfAuto() {
+	if !j.autoSync {
+		return
+	}
+
+	if err := j.Sync(); err != nil {
+		j.logger.Error(context.Background(), err.Error())
 	}
 }
 
@@ -59,23 +73,78 @@ func (j *PersistentJar) Cookies(u *url.URL) []*http.Cookie {
 	return j.jar.Cookies(u)
 }
 
-// Sync persists cookies to the file.
+// Sync persists cookies to the file. A jar that was not used yet loads the file first, so that it does not overwrite it
+// with no cookies.
 func (j *PersistentJar) Sync() error {
+	j.lazyLoad.Do(j.load)
+
 	j.jar.mu.Lock()
 	defer j.jar.mu.Unlock()
 
 	ctx := ctxd.AddFields(context.Background(), "cookies.file", j.filePath)
 
-	f, err := j.fs.OpenFile(filepath.Clean(j.filePath), os.O_RDWR|os.O_CREATE|os.O_TRUNC, j.filePerm)
+	// The cookies that could not be loaded, e.g. with a wrong key, must not be overwritten by an empty jar.
+	if j.loadErr != nil {
+		return ctxd.WrapError(ctx, j.loadErr, "could not persist cookies that were not loaded")
+	}
+
+	// The last access times are persisted with the checkpoint or the files of the sites, not journaled on every read.
+	if err := j.jar.flushAccessed(); err != nil {
+		return ctxd.WrapError(ctx, err, "could not persist cookies")
+	}
+
+	if j.journal != nil {
+		return j.syncJournal(ctx)
+	}
+
+	if j.sites != nil {
+		if err := j.sites.sync(); err != nil {
+			return ctxd.WrapError(ctx, err, "could not persist cookies")
+		}
+
+		return nil
+	}
+
+	// The cookies are written to a temporary file that replaces the file, so that a failure does not truncate it.
+	tmpPath := filepath.Clean(j.filePath) + ".tmp"
+
+	f, err := j.fs.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, j.filePerm)
 	if err != nil {
 		return ctxd.WrapError(ctx, err, "could not open file for persisting cookies")
 	}
 
-	defer func() {
-		_ = f.Close() //nolint: errcheck
-	}()
+	err = j.writeFile(ctx, f)
+	if closeErr := f.Close(); err == nil && closeErr != nil {
+		err = ctxd.WrapError(ctx, closeErr, "could not close cookies file")
+	}
+
+	if err != nil {
+		_ = j.fs.Remove(tmpPath) //nolint: errcheck
+
+		return err
+	}
 
-	if err := j.serder.Serialize(f, mapToExport(j.jar.entries)); err != nil {
+	if err := j.fs.Rename(tmpPath, filepath.Clean(j.filePath)); err != nil {
+		return ctxd.WrapError(ctx, err, "could not replace cookies file")
+	}
+
+	return nil
+}
+
+// writeFile serializes the cookies to f and flushes it to the disk. The caller must hold j.jar.mu.
+func (j *PersistentJar) writeFile(ctx context.Context, f afero.File) error {
+	entries, err := j.jar.allEntries()
+	if err != nil {
+		return ctxd.WrapError(ctx, err, "could not read cookies from store")
+	}
+
+	if s, ok := j.serder.(StreamEntrySerDer); ok {
+		err = s.SerializeSeq(f, exportSeq(entries))
+	} else {
+		err = j.serder.Serialize(f, mapToExport(entries))
+	}
+
+	if err != nil {
 		return ctxd.WrapError(ctx, err, "could not serialize cookies")
 	}
 
@@ -87,15 +156,85 @@ func (j *PersistentJar) Sync() error {
 }
 
 func (j *PersistentJar) load() {
+	// The sites are loaded lazily by their store.
+	if j.sites != nil {
+		return
+	}
+
 	j.jar.mu.Lock()
 	defer j.jar.mu.Unlock()
 
 	ctx := ctxd.AddFields(context.Background(), "cookies.file", j.filePath)
 
+	if s, ok := j.serder.(StreamEntrySerDer); ok && j.journal == nil {
+		j.loadSeq(ctx, s)
+
+		return
+	}
+
+	entries, err := j.readFile(ctx)
+	if err != nil {
+		j.loadErr = err
+
+		return
+	}
+
+	// The checkpoint is re-keyed before the journal is replayed, the records of the journal are keyed with the public
+	// suffix list of the jar and would miss the entries of a checkpoint keyed with another one.
+	loaded := entries
+	entries, report := rekeyEntries(loaded, j.jar.psList)
+
+	j.jar.auditDropped(loaded, report.Dropped)
+
+	if j.journal != nil {
+		if err := j.journal.replay(ctx, entries, j.jar.psList, j.logger); err != nil {
+			j.logger.Error(ctx, "could not replay cookies journal", "error", err)
+		}
+
+		// The replayed records are keyed already, only the entries that the public suffix list makes illegal are left.
+		var replayed RekeyReport
+
+		loaded := entries
+		entries, replayed = rekeyEntries(loaded, j.jar.psList)
+
+		j.jar.auditDropped(loaded, replayed.Dropped)
+
+		report.Dropped = append(report.Dropped, replayed.Dropped...)
+	}
+
+	j.jar.sealEntries(entries)
+
+	if !report.IsEmpty() {
+		j.logger.Warn(ctx, "re-keyed cookies with the public suffix list",
+			"cookies.moved", len(report.Moved),
+			"cookies.dropped", len(report.Dropped),
+		)
+
+		if j.onRekey != nil {
+			j.onRekey(report)
+		}
+	}
+
+	if j.journal != nil {
+		j.journal.entries = entries
+		_, j.jar.nextSeqNum = mapToImport(entries)
+
+		return
+	}
+
+	j.jar.entries, j.jar.nextSeqNum = mapToImport(entries)
+}
+
+// loadSeq reads the entries one by one into the jar, without holding a second copy of the jar in memory. The keys are
+// computed with the public suffix list of the jar and the entries that it makes illegal are dropped. The errors are
+// logged and the jar is left empty.
+func (j *PersistentJar) loadSeq(ctx context.Context, s StreamEntrySerDer) {
 	f, err := j.fs.Open(filepath.Clean(j.filePath))
 	if err != nil {
 		if !errors.Is(err, os.ErrNotExist) {
 			j.logger.Error(ctx, "could not open file for loading cookies", "error", err)
+
+			j.loadErr = err
 		}
 
 		return
@@ -105,14 +244,136 @@ func (j *PersistentJar) load() {
 		_ = f.Close() //nolint: errcheck
 	}()
 
+	var report RekeyReport
+
+	dropped := make(map[string]map[string]Entry)
+
+	defer func() {
+		j.jar.auditDropped(dropped, report.Dropped)
+	}()
+
+	for e, err := range s.DeserializeSeq(f) {
+		if err != nil {
+			j.logger.Error(ctx, "could not deserialize cookies", "error", err)
+
+			j.jar.entries = make(map[string]map[string]entry)
+			j.jar.nextSeqNum = 0
+			j.loadErr = err
+
+			return
+		}
+
+		if !isLegalDomain(e.Domain, e.HostOnly, j.jar.psList) {
+			d := RekeyedEntry{ID: e.ID(), OldKey: jarKey(e.Domain, nil)}
+			report.Dropped = append(report.Dropped, d)
+
+			if dropped[d.OldKey] == nil {
+				dropped[d.OldKey] = make(map[string]Entry)
+			}
+
+			dropped[d.OldKey][d.ID] = e
+
+			continue
+		}
+
+		e, _ = j.jar.sealExported(e)
+		key := jarKey(e.Domain, j.jar.psList)
+
+		if j.jar.entries[key] == nil {
+			j.jar.entries[key] = make(map[string]entry)
+		}
+
+		j.jar.entries[key][e.ID()] = importEntry(e)
+
+		if e.SeqNum >= j.jar.nextSeqNum {
+			j.jar.nextSeqNum = e.SeqNum + 1
+		}
+	}
+
+	if !report.IsEmpty() {
+		j.logger.Warn(ctx, "re-keyed cookies with the public suffix list", "cookies.dropped", len(report.Dropped))
+
+		if j.onRekey != nil {
+			j.onRekey(report)
+		}
+	}
+}
+
+// readFile reads the entries from the file. A missing file has no entries. The errors are logged.
+func (j *PersistentJar) readFile(ctx context.Context) (map[string]map[string]Entry, error) {
+	f, err := j.fs.Open(filepath.Clean(j.filePath))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			j.logger.Error(ctx, "could not open file for loading cookies", "error", err)
+
+			return nil, err
+		}
+
+		return make(map[string]map[string]Entry), nil
+	}
+
+	defer func() {
+		_ = f.Close() //nolint: errcheck
+	}()
+
+	if j.journal != nil {
+		if fi, err := f.Stat(); err == nil {
+			j.journal.checkpointSize = fi.Size()
+		}
+	}
+
 	entries, err := j.serder.Deserialize(f)
 	if err != nil {
 		j.logger.Error(ctx, "could not deserialize cookies", "error", err)
 
-		return
+		return nil, err
 	}
 
-	j.jar.entries, j.jar.nextSeqNum = mapToImport(entries)
+	if entries == nil {
+		entries = make(map[string]map[string]Entry)
+	}
+
+	return entries, nil
+}
+
+// Close persists cookies to the file, see Sync, and closes the journal file. The jar can still be used, the journal is
+// opened again by the next change.
+func (j *PersistentJar) Close() error {
+	if err := j.Sync(); err != nil {
+		return err
+	}
+
+	if j.journal == nil {
+		return nil
+	}
+
+	j.jar.mu.Lock()
+	defer j.jar.mu.Unlock()
+
+	ctx := ctxd.AddFields(context.Background(), "cookies.file", j.filePath)
+
+	if err := j.journal.close(); err != nil {
+		return ctxd.WrapError(ctx, err, "could not close cookies journal")
+	}
+
+	return nil
+}
+
+// syncJournal flushes the journal to the disk, or compacts it to a new checkpoint once it passes the threshold.
+func (j *PersistentJar) syncJournal(ctx context.Context) error {
+	if !j.journal.needsCompaction() {
+		if err := j.journal.sync(); err != nil {
+			return ctxd.WrapError(ctx, err, "could not sync cookies journal")
+		}
+
+		return nil
+	}
+
+	if err := j.journal.compact(j.serder, j.filePath, j.filePerm); err != nil {
+		return ctxd.WrapError(ctx, err, "could not compact cookies journal")
+	}
+
+	return nil
 }
 
 // NewPersistentJar creates new persistent cookie jar.
@@ -133,6 +394,36 @@ func NewPersistentJar(opts ...PersistentJarOption) *PersistentJar {
 		opt.applyPersistentJarOption(j)
 	}
 
+	if j.sites != nil {
+		j.sites.fs = j.fs
+		j.sites.dir = j.filePath
+		j.sites.filePerm = j.filePerm
+		j.sites.serder = j.serder
+		j.sites.psList = j.jar.psList
+		j.sites.logger = j.logger
+		j.sites.onRekey = j.onRekey
+		j.sites.seal = j.jar.sealExported
+		j.sites.onDrop = j.jar.auditDropped
+
+		j.jar.store = j.sites
+		j.jar.onStoreError = func(err error) {
+			j.logger.Error(context.Background(), "could not load cookies", "error", err, "cookies.file", j.filePath)
+		}
+	} else if j.journal != nil {
+		j.journal.fs = j.fs
+		j.journal.path = j.filePath + ".journal"
+		j.journal.filePerm = j.filePerm
+
+		j.jar.store = j.journal
+		j.jar.onStoreError = func(err error) {
+			j.logger.Error(context.Background(), "could not journal cookies", "error", err, "cookies.file", j.filePath)
+		}
+	} else if j.jar.sealer != nil || j.jar.audit != nil {
+		j.jar.onStoreError = func(err error) {
+			j.logger.Error(context.Background(), "could not update cookies", "error", err, "cookies.file", j.filePath)
+		}
+	}
+
 	return j
 }
 
@@ -196,22 +487,92 @@ func WithPublicSuffixList(list PublicSuffixList) PersistentJarOption {
 	})
 }
 
+// WithRekeyReporter sets a function that is called with the changes made to the loaded cookies when their eTLD+1 keys do
+// not match the public suffix list.
+func WithRekeyReporter(fn func(report RekeyReport)) PersistentJarOption {
+	return persistentJarOptionFunc(func(j *PersistentJar) {
+		j.onRekey = fn
+	})
+}
+
+// WithJournal turns on the journal mode. Every change of the cookies is appended to a JSON-lines journal next to the
+// file, "<file>.journal", and the file becomes a checkpoint. Sync flushes the journal and, once the journal is larger than
+// compactSize bytes or than the checkpoint times compactRatio, compacts it: the checkpoint is rewritten and the journal
+// is truncated. The last access times of the cookies are not journaled, they are persisted by the compaction. Zero
+// values use a compactSize of 4 MiB and a compactRatio of 1.
+func WithJournal(compactSize int64, compactRatio float64) PersistentJarOption {
+	return persistentJarOptionFunc(func(j *PersistentJar) {
+		if compactSize <= 0 {
+			compactSize = defaultJournalCompactSize
+		}
+
+		if compactRatio <= 0 {
+			compactRatio = defaultJournalCompactRatio
+		}
+
+		j.journal = &journal{
+			compactSize:  compactSize,
+			compactRatio: compactRatio,
+			entries:      make(map[string]map[string]Entry),
+		}
+	})
+}
+
+// WithSiteFiles turns on the per-site layout. The file path is a directory with one file per eTLD+1, named after the
+// eTLD+1 with the ".cookies" extension. The file of a site is read the first time the site is accessed and Sync only
+// writes the sites that changed. The journal mode is ignored in this layout.
+func WithSiteFiles() PersistentJarOption {
+	return persistentJarOptionFunc(func(j *PersistentJar) {
+		j.sites = newSiteFiles()
+	})
+}
+
+// WithSealer seals the values of the cookies that match the rules of s, in memory and in the file. The values of the
+// cookies that are loaded in plain text are sealed when they are loaded. See [Sealer].
+func WithSealer(s *Sealer) PersistentJarOption {
+	return persistentJarOptionFunc(func(j *PersistentJar) {
+		j.jar.sealer = s
+	})
+}
+
+// WithAudit records every change of the cookies with sink, see [AuditSink].
+func WithAudit(sink AuditSink) PersistentJarOption {
+	return persistentJarOptionFunc(func(j *PersistentJar) {
+		j.jar.audit = sink
+	})
+}
+
+// WithAuditHashKey sets the key of the HMAC of the values in the audit records, so that they can be compared across
+// jars and restarts. Without it, a random key is used for the life of the jar.
+func WithAuditHashKey(key []byte) PersistentJarOption {
+	return persistentJarOptionFunc(func(j *PersistentJar) {
+		j.jar.auditKey = slices.Clone(key)
+	})
+}
+
 // Entry is a public presentation of the entry struct.
 type Entry struct {
-	Name       string
-	Value      string
-	Quoted     bool
-	Domain     string
-	Path       string
-	SameSite   string
-	Secure     bool
-	HttpOnly   bool
-	Persistent bool
-	HostOnly   bool
-	Expires    time.Time
-	Creation   time.Time
-	LastAccess time.Time
-	SeqNum     uint64
+	Name         string
+	Value        string
+	Quoted       bool
+	Domain       string
+	Path         string
+	SameSite     string
+	Secure       bool
+	HttpOnly     bool
+	Persistent   bool
+	HostOnly     bool
+	Partitioned  bool
+	PartitionKey string
+	Expires      time.Time
+	Creation     time.Time
+	LastAccess   time.Time
+	SeqNum       uint64
+}
+
+// ID returns the domain;path;name triple of e, the key of e in the entries of its eTLD+1.
+func (e Entry) ID() string {
+	return entryID(e.Domain, e.Path, e.Name)
 }
 
 func mapToExport(entries map[string]map[string]entry) map[string]map[string]Entry {
@@ -221,27 +582,28 @@ func mapToExport(entries map[string]map[string]entry) map[string]map[string]Entr
 		exported[domain] = make(map[string]Entry)
 
 		for name, cookie := range domainCookies {
-			exported[domain][name] = Entry{
-				Name:       cookie.Name,
-				Value:      cookie.Value,
-				Domain:     cookie.Domain,
-				Path:       cookie.Path,
-				SameSite:   cookie.SameSite,
-				Secure:     cookie.Secure,
-				HttpOnly:   cookie.HttpOnly,
-				Persistent: cookie.Persistent,
-				HostOnly:   cookie.HostOnly,
-				Expires:    cookie.Expires,
-				Creation:   cookie.Creation,
-				LastAccess: cookie.LastAccess,
-				SeqNum:     cookie.seqNum,
-			}
+			exported[domain][name] = exportEntry(cookie)
 		}
 	}
 
 	return exported
 }
 
+// exportSeq returns the exported entries one by one, sorted by key and id, without copying the map.
+func exportSeq(entries map[string]map[string]entry) iter.Seq[Entry] {
+	return func(yield func(Entry) bool) {
+		for _, key := range sortedKeys(entries) {
+			submap := entries[key]
+
+			for _, id := range sortedKeys(submap) {
+				if !yield(exportEntry(submap[id])) {
+					return
+				}
+			}
+		}
+	}
+}
+
 func mapToImport(entries map[string]map[string]Entry) (map[string]map[string]entry, uint64) {
 	nextSeqNum := uint64(0)
 	imported := make(map[string]map[string]entry)
@@ -250,21 +612,7 @@ func mapToImport(entries map[string]map[string]Entry) (map[string]map[string]ent
 		imported[domain] = make(map[string]entry)
 
 		for name, cookie := range domainCookies {
-			imported[domain][name] = entry{
-				Name:       cookie.Name,
-				Value:      cookie.Value,
-				Domain:     cookie.Domain,
-				Path:       cookie.Path,
-				SameSite:   cookie.SameSite,
-				Secure:     cookie.Secure,
-				HttpOnly:   cookie.HttpOnly,
-				Persistent: cookie.Persistent,
-				HostOnly:   cookie.HostOnly,
-				Expires:    cookie.Expires,
-				Creation:   cookie.Creation,
-				LastAccess: cookie.LastAccess,
-				seqNum:     cookie.SeqNum,
-			}
+			imported[domain][name] = importEntry(cookie)
 
 			if cookie.SeqNum > nextSeqNum {
 				nextSeqNum = cookie.SeqNum + 1
@@ -275,12 +623,84 @@ func mapToImport(entries map[string]map[string]Entry) (map[string]map[string]ent
 	return imported, nextSeqNum
 }
 
+func exportEntry(e entry) Entry {
+	return Entry{
+		Name:         e.Name,
+		Value:        e.Value,
+		Quoted:       e.Quoted,
+		Domain:       e.Domain,
+		Path:         e.Path,
+		SameSite:     e.SameSite,
+		Secure:       e.Secure,
+		HttpOnly:     e.HttpOnly,
+		Persistent:   e.Persistent,
+		HostOnly:     e.HostOnly,
+		Partitioned:  e.Partitioned,
+		PartitionKey: e.PartitionKey,
+		Expires:      e.Expires,
+		Creation:     e.Creation,
+		LastAccess:   e.LastAccess,
+		SeqNum:       e.seqNum,
+	}
+}
+
+func importEntry(e Entry) entry {
+	return entry{
+		Name:         e.Name,
+		Value:        e.Value,
+		Quoted:       e.Quoted,
+		Domain:       e.Domain,
+		Path:         e.Path,
+		SameSite:     e.SameSite,
+		Secure:       e.Secure,
+		HttpOnly:     e.HttpOnly,
+		Persistent:   e.Persistent,
+		HostOnly:     e.HostOnly,
+		Partitioned:  e.Partitioned,
+		PartitionKey: e.PartitionKey,
+		Expires:      e.Expires,
+		Creation:     e.Creation,
+		LastAccess:   e.LastAccess,
+		seqNum:       e.SeqNum,
+	}
+}
+
 // EntrySerDer is an interface for serializing and deserializing entries.
+//
+// The entries are keyed by their eTLD+1. A format that does not store the keys deserializes them keyed without a public
+// suffix list, PersistentJar re-keys the loaded entries with its own list before anything else, see rekeyEntries.
 type EntrySerDer interface {
 	Serialize(w io.Writer, entries map[string]map[string]Entry) error
 	Deserialize(r io.Reader) (map[string]map[string]Entry, error)
 }
 
+// StreamEntrySerDer is an EntrySerDer that also reads and writes the entries one by one. PersistentJar uses it instead
+// of Serialize and Deserialize, so that a large jar is not copied in memory when it is loaded or persisted.
+//
+// The entries do not carry their eTLD+1 key, it is computed with the public suffix list of the jar when loading.
+type StreamEntrySerDer interface {
+	EntrySerDer
+
+	SerializeSeq(w io.Writer, entries iter.Seq[Entry]) error
+	DeserializeSeq(r io.Reader) iter.Seq2[Entry, error]
+}
+
+// serDerCopier is an EntrySerDer that keeps a state between the calls, e.g. the origins of the Playwright
+// serializer/deserializer. The serializers/deserializers that wrap another one are copiers too.
+type serDerCopier interface {
+	// copySerDer returns a new serializer/deserializer with the same configuration and without the state.
+	copySerDer() EntrySerDer
+}
+
+// copySerDer returns a copy of serder without its state, or serder if it does not have one.
+func copySerDer(serder EntrySerDer) EntrySerDer {
+	if c, ok := serder.(serDerCopier); ok {
+		return c.copySerDer()
+	}
+
+	return serder
+}
+
 // jsonSerDer is a JSON serializer and deserializer.
 type jsonSerDer struct{}
 
@@ -1,8 +1,11 @@
 package cookiejar
 
 import (
+	"net/http"
+	"net/url"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -32,3 +35,71 @@ func TestEntry_HasSameProps(t *testing.T) {
 
 	assert.Equalf(t, exported, unexported, "exported and unexported entries must be the same")
 }
+
+func TestSiteFileName(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		key      string
+		expected string
+	}{
+		{key: "example.com", expected: "example.com.cookies"},
+		{key: "xn--bcher-kva.example", expected: "xn--bcher-kva.example.cookies"},
+		{key: "::1", expected: "%3A%3A1.cookies"},
+		{key: "..", expected: "%2E..cookies"},
+		{key: "a/b\\c", expected: "a%2Fb%5Cc.cookies"},
+		{key: "", expected: ".cookies"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.key, func(t *testing.T) {
+			t.Parallel()
+
+			name := siteFileName(tc.key)
+
+			assert.Equal(t, tc.expected, name)
+
+			key, ok := siteKey(name)
+
+			assert.True(t, ok)
+			assert.Equal(t, tc.key, key)
+		})
+	}
+
+	for _, name := range []string{"cookies.json", "%zz.cookies", "%2.cookies", "Example.com.cookies"} {
+		_, ok := siteKey(name)
+
+		assert.False(t, ok, name)
+	}
+}
+
+func TestJar_HARCookies_Expiration(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	u := &url.URL{Scheme: "https", Host: "example.com", Path: "/"}
+
+	jar, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jar.setCookies(u, []*http.Cookie{
+		{Name: "short", Value: "1", MaxAge: 10},
+		{Name: "long", Value: "2", Path: "/", MaxAge: 60},
+	}, now)
+
+	expected := []HARCookie{
+		{Name: "short", Value: "1", Path: "/", Expires: "2024-01-01T00:00:10Z"},
+		{Name: "long", Value: "2", Path: "/", Expires: "2024-01-01T00:01:00Z"},
+	}
+
+	assert.Equal(t, expected, jar.harCookies(u, now.Add(5*time.Second)))
+
+	expected = []HARCookie{
+		{Name: "long", Value: "2", Path: "/", Expires: "2024-01-01T00:01:00Z"},
+	}
+
+	assert.Equal(t, expected, jar.harCookies(u, now.Add(10*time.Second)))
+	assert.Empty(t, jar.harCookies(u, now.Add(time.Minute)))
+}
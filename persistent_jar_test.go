@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/bool64/ctxd"
@@ -54,8 +55,11 @@ func TestPersistentJar_SetCookies_NoAutoSync_Success(t *testing.T) {
 		fs.On("Open", filePath).Once().
 			Return(mem.NewFileHandle(fileData), nil)
 
-		fs.On("OpenFile", filePath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, os.FileMode(0o755)).Once().
+		fs.On("OpenFile", filePath+".tmp", os.O_RDWR|os.O_CREATE|os.O_TRUNC, os.FileMode(0o755)).Once().
 			Return(mem.NewFileHandle(fileData), nil)
+
+		fs.On("Rename", filePath+".tmp", filePath).Once().
+			Return(nil)
 	})(t)
 
 	j := cookiejar.NewPersistentJar(
@@ -111,6 +115,8 @@ func TestPersistentJar_SetCookies_NoAutoSync_Success(t *testing.T) {
       "HttpOnly": false,
       "Persistent": false,
       "HostOnly": true,
+      "Partitioned": false,
+      "PartitionKey": "",
       "Expires": "9999-12-31T23:59:59Z",
       "Creation": "<ignore-diff>",
       "LastAccess": "<ignore-diff>",
@@ -127,6 +133,8 @@ func TestPersistentJar_SetCookies_NoAutoSync_Success(t *testing.T) {
       "HttpOnly": false,
       "Persistent": false,
       "HostOnly": false,
+      "Partitioned": false,
+      "PartitionKey": "",
       "Expires": "0001-01-01T00:00:00Z",
       "Creation": "<ignore-diff>",
       "LastAccess": "<ignore-diff>",
@@ -143,6 +151,8 @@ func TestPersistentJar_SetCookies_NoAutoSync_Success(t *testing.T) {
       "HttpOnly": false,
       "Persistent": false,
       "HostOnly": true,
+      "Partitioned": false,
+      "PartitionKey": "",
       "Expires": "9999-12-31T23:59:59Z",
       "Creation": "<ignore-diff>",
       "LastAccess": "<ignore-diff>",
@@ -184,6 +194,9 @@ func TestPersistentJar_SetCookies_AutoSync_Error(t *testing.T) {
 
 				fs.On("OpenFile", mock.Anything, mock.Anything, mock.Anything).Once().
 					Return(f, nil)
+
+				fs.On("Remove", filePath+".tmp").Once().
+					Return(nil)
 			}),
 		},
 		{
@@ -199,6 +212,9 @@ func TestPersistentJar_SetCookies_AutoSync_Error(t *testing.T) {
 
 				fs.On("OpenFile", mock.Anything, mock.Anything, mock.Anything).Once().
 					Return(f, nil)
+
+				fs.On("Remove", filePath+".tmp").Once().
+					Return(nil)
 			}),
 		},
 	}
@@ -236,8 +252,11 @@ func TestPersistentJar_SetCookies_AutoSync_Success(t *testing.T) {
 		fs.On("Open", filePath).Once().
 			Return(nil, os.ErrNotExist)
 
-		fs.On("OpenFile", filePath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, os.FileMode(0o755)).Once().
+		fs.On("OpenFile", filePath+".tmp", os.O_RDWR|os.O_CREATE|os.O_TRUNC, os.FileMode(0o755)).Once().
 			Return(mem.NewFileHandle(fileData), nil)
+
+		fs.On("Rename", filePath+".tmp", filePath).Once().
+			Return(nil)
 	})(t)
 
 	j := cookiejar.NewPersistentJar(
@@ -268,6 +287,8 @@ func TestPersistentJar_SetCookies_AutoSync_Success(t *testing.T) {
       "HttpOnly": false,
       "Persistent": false,
       "HostOnly": true,
+      "Partitioned": false,
+      "PartitionKey": "",
       "Expires": "9999-12-31T23:59:59Z",
       "Creation": "<ignore-diff>",
       "LastAccess": "<ignore-diff>",
@@ -351,6 +372,143 @@ func TestPersistentJar_Cookies(t *testing.T) {
 	}
 }
 
+func TestPersistentJar_Cookies_Rekey(t *testing.T) {
+	t.Parallel()
+
+	const filePath = "/tmp/cookies.json"
+
+	// The keys were computed without a public suffix list.
+	const fileContent = `{
+  "co.uk": {
+    "www.example.co.uk;/;id": {
+      "Name": "id",
+      "Value": "42",
+      "Domain": "www.example.co.uk",
+      "Path": "/",
+      "HostOnly": true,
+      "Partitioned": false,
+      "PartitionKey": "",
+      "Expires": "9999-12-31T23:59:59Z",
+      "SeqNum": 0
+    },
+    "co.uk;/;tracker": {
+      "Name": "tracker",
+      "Value": "1",
+      "Domain": "co.uk",
+      "Path": "/",
+      "HostOnly": false,
+      "Partitioned": false,
+      "PartitionKey": "",
+      "Expires": "9999-12-31T23:59:59Z",
+      "SeqNum": 1
+    }
+  }
+}`
+
+	fs := aferomock.MockFs(func(fs *aferomock.Fs) {
+		f := mem.NewFileHandle(mem.CreateFile("cookies.json"))
+		_, _ = f.WriteString(fileContent) //nolint: errcheck
+		_, _ = f.Seek(0, io.SeekStart)    //nolint: errcheck
+
+		fs.On("Open", filePath).Once().
+			Return(f, nil)
+	})(t)
+
+	var report cookiejar.RekeyReport
+
+	j := cookiejar.NewPersistentJar(
+		cookiejar.WithFs(fs),
+		cookiejar.WithFilePath(filePath),
+		cookiejar.WithPublicSuffixList(suffixList{"co.uk"}),
+		cookiejar.WithRekeyReporter(func(r cookiejar.RekeyReport) {
+			report = r
+		}),
+	)
+
+	u := &url.URL{Scheme: "https", Host: "www.example.co.uk"}
+
+	actual := j.Cookies(u)
+	expected := []*http.Cookie{{
+		Name:  "id",
+		Value: "42",
+	}}
+
+	assert.Equal(t, expected, actual)
+
+	expectedReport := cookiejar.RekeyReport{
+		Moved: []cookiejar.RekeyedEntry{
+			{ID: "www.example.co.uk;/;id", OldKey: "co.uk", NewKey: "example.co.uk"},
+		},
+		Dropped: []cookiejar.RekeyedEntry{
+			{ID: "co.uk;/;tracker", OldKey: "co.uk"},
+		},
+	}
+
+	assert.Equal(t, expectedReport, report)
+}
+
+func TestPersistentJar_Cookies_RekeyCollision(t *testing.T) {
+	t.Parallel()
+
+	const filePath = "/tmp/cookies.json"
+
+	// The cookie was stored without a public suffix list, then again under its right key by a newer version.
+	const fileContent = `{
+  "co.uk": {
+    "www.example.co.uk;/;id": {
+      "Name": "id",
+      "Value": "old",
+      "Domain": "www.example.co.uk",
+      "Path": "/",
+      "HostOnly": true,
+      "Expires": "9999-12-31T23:59:59Z",
+      "Creation": "2024-01-01T00:00:00Z",
+      "SeqNum": 5
+    }
+  },
+  "example.co.uk": {
+    "www.example.co.uk;/;id": {
+      "Name": "id",
+      "Value": "new",
+      "Domain": "www.example.co.uk",
+      "Path": "/",
+      "HostOnly": true,
+      "Expires": "9999-12-31T23:59:59Z",
+      "Creation": "2024-06-01T00:00:00Z",
+      "SeqNum": 0
+    }
+  }
+}`
+
+	fs := afero.NewMemMapFs()
+
+	require.NoError(t, afero.WriteFile(fs, filePath, []byte(fileContent), 0o600))
+
+	var report cookiejar.RekeyReport
+
+	j := cookiejar.NewPersistentJar(
+		cookiejar.WithFs(fs),
+		cookiejar.WithFilePath(filePath),
+		cookiejar.WithPublicSuffixList(suffixList{"co.uk"}),
+		cookiejar.WithRekeyReporter(func(r cookiejar.RekeyReport) {
+			report = r
+		}),
+	)
+
+	actual := j.Cookies(&url.URL{Scheme: "https", Host: "www.example.co.uk"})
+	expected := []*http.Cookie{{Name: "id", Value: "new"}}
+
+	assert.Equal(t, expected, actual)
+
+	expectedReport := cookiejar.RekeyReport{
+		Dropped: []cookiejar.RekeyedEntry{
+			{ID: "www.example.co.uk;/;id", OldKey: "co.uk"},
+		},
+	}
+
+	assert.Equal(t, expectedReport, report)
+}
+
 func TestPersistentJar_Sync(t *testing.T) {
 	t.Parallel()
 
@@ -364,6 +522,9 @@ func TestPersistentJar_Sync(t *testing.T) {
 		{
 			scenario: "could not open file for writing",
 			mockFs: aferomock.MockFs(func(fs *aferomock.Fs) {
+				fs.On("Open", filePath).Once().
+					Return(nil, os.ErrNotExist)
+
 				fs.On("OpenFile", mock.Anything, mock.Anything, mock.Anything).Once().
 					Return(nil, errors.New("open file error"))
 			}),
@@ -372,17 +533,26 @@ func TestPersistentJar_Sync(t *testing.T) {
 		{
 			scenario: "could not encode cookies",
 			mockFs: aferomock.MockFs(func(fs *aferomock.Fs) {
+				fs.On("Open", filePath).Once().
+					Return(nil, os.ErrNotExist)
+
 				f := mem.NewFileHandle(mem.CreateFile("test"))
 				_ = f.Close() //nolint: errcheck
 
 				fs.On("OpenFile", mock.Anything, mock.Anything, mock.Anything).Once().
 					Return(f, nil)
+
+				fs.On("Remove", filePath+".tmp").Once().
+					Return(nil)
 			}),
 			expectedError: "could not serialize cookies: File is closed",
 		},
 		{
 			scenario: "could not sync file",
 			mockFs: aferomock.MockFs(func(fs *aferomock.Fs) {
+				fs.On("Open", filePath).Once().
+					Return(nil, os.ErrNotExist)
+
 				f := &fileWithSyncError{
 					File:      mem.NewFileHandle(mem.CreateFile("test")),
 					SyncError: errors.New("sync error"),
@@ -390,14 +560,23 @@ func TestPersistentJar_Sync(t *testing.T) {
 
 				fs.On("OpenFile", mock.Anything, mock.Anything, mock.Anything).Once().
 					Return(f, nil)
+
+				fs.On("Remove", filePath+".tmp").Once().
+					Return(nil)
 			}),
 			expectedError: "could not sync cookies file: sync error",
 		},
 		{
 			scenario: "success",
 			mockFs: aferomock.MockFs(func(fs *aferomock.Fs) {
+				fs.On("Open", filePath).Once().
+					Return(nil, os.ErrNotExist)
+
 				fs.On("OpenFile", mock.Anything, mock.Anything, mock.Anything).Once().
 					Return(mem.NewFileHandle(mem.CreateFile("test")), nil)
+
+				fs.On("Rename", filePath+".tmp", filePath).Once().
+					Return(nil)
 			}),
 		},
 	}
@@ -425,6 +604,29 @@ func TestPersistentJar_Sync(t *testing.T) {
 	}
 }
 
+func TestPersistentJar_Sync_NotLoaded(t *testing.T) {
+	t.Parallel()
+
+	const filePath = "/tmp/cookies.json"
+
+	fs := afero.NewMemMapFs()
+	u := &url.URL{Scheme: "https", Host: "example.com", Path: "/"}
+
+	newJar := func() *cookiejar.PersistentJar {
+		return cookiejar.NewPersistentJar(cookiejar.WithFs(fs), cookiejar.WithFilePath(filePath))
+	}
+
+	j := newJar()
+
+	j.SetCookies(u, []*http.Cookie{{Name: "id", Value: "42"}})
+	require.NoError(t, j.Sync())
+
+	// A jar that was not used yet does not overwrite the file with no cookies.
+	require.NoError(t, newJar().Sync())
+
+	assert.Equal(t, []*http.Cookie{{Name: "id", Value: "42"}}, newJar().Cookies(u))
+}
+
 func TestWithSerDer(t *testing.T) {
 	t.Parallel()
 
@@ -510,3 +712,24 @@ func (s *serder) Serialize(w io.Writer, entries map[string]map[string]cookiejar.
 func (s *serder) Deserialize(r io.Reader) (map[string]map[string]cookiejar.Entry, error) {
 	return s.deserialize(r)
 }
+
+// suffixList is a public suffix list that knows only the given suffixes.
+type suffixList []string
+
+func (l suffixList) PublicSuffix(domain string) string {
+	for _, suffix := range l {
+		if domain == suffix || strings.HasSuffix(domain, "."+suffix) {
+			return suffix
+		}
+	}
+
+	if i := strings.LastIndex(domain, "."); i >= 0 {
+		return domain[i+1:]
+	}
+
+	return domain
+}
+
+func (l suffixList) String() string {
+	return strings.Join(l, ",")
+}
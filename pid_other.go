@@ -0,0 +1,18 @@
+//go:build !unix
+
+package cookiejar
+
+import "os"
+
+// processAlive reports whether the process pid is running on this host. On the systems where it cannot be told, the
+// process is reported as running.
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+
+	_ = p.Release() //nolint: errcheck
+
+	return true
+}
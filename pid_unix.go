@@ -0,0 +1,15 @@
+//go:build unix
+
+package cookiejar
+
+import (
+	"errors"
+	"syscall"
+)
+
+// processAlive reports whether the process pid is running on this host.
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
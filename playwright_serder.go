@@ -0,0 +1,170 @@
+package cookiejar
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+var _ EntrySerDer = (*playwrightSerDer)(nil)
+
+// playwrightStorageState is the storageState of Playwright.
+type playwrightStorageState struct {
+	Cookies []playwrightCookie `json:"cookies"`
+	Origins json.RawMessage    `json:"origins"`
+}
+
+// playwrightCookie is a cookie in the storageState of Playwright.
+type playwrightCookie struct {
+	Name         string  `json:"name"`
+	Value        string  `json:"value"`
+	Domain       string  `json:"domain"`
+	Path         string  `json:"path"`
+	Expires      float64 `json:"expires"`
+	HTTPOnly     bool    `json:"httpOnly"`
+	Secure       bool    `json:"secure"`
+	SameSite     string  `json:"sameSite"`
+	PartitionKey string  `json:"partitionKey,omitempty"`
+}
+
+// playwrightSerDer is a serializer and deserializer for the storageState JSON of Playwright.
+//
+// The origins, with their localStorage, are not cookies. They are kept from the last deserialized state and written
+// back as they are, so that a state survives a round-trip through PersistentJar. Profiles copies it for every jar, see
+// serDerCopier.
+type playwrightSerDer struct {
+	mu      sync.Mutex
+	origins json.RawMessage
+}
+
+// NewPlaywrightSerDer returns a serializer/deserializer for the storageState JSON of Playwright.
+//
+// The format does not have the eTLD+1 keys, see [EntrySerDer], nor creation and last access time.
+//
+// The origins of the state, with their localStorage, are kept from the last Deserialize and written back by Serialize.
+// A serializer/deserializer must therefore not be shared between jars, Profiles makes a new one for every profile, nor
+// be used with WithSiteFiles.
+func NewPlaywrightSerDer() EntrySerDer {
+	return &playwrightSerDer{}
+}
+
+func (s *playwrightSerDer) copySerDer() EntrySerDer {
+	return &playwrightSerDer{}
+}
+
+func (s *playwrightSerDer) Serialize(w io.Writer, entries map[string]map[string]Entry) error {
+	s.mu.Lock()
+	origins := s.origins
+	s.mu.Unlock()
+
+	if origins == nil {
+		origins = json.RawMessage(`[]`)
+	}
+
+	state := playwrightStorageState{
+		Cookies: make([]playwrightCookie, 0),
+		Origins: origins,
+	}
+
+	for _, e := range sortedEntries(entries) {
+		c := playwrightCookie{
+			Name:         e.Name,
+			Value:        e.Value,
+			Domain:       e.Domain,
+			Path:         e.Path,
+			Expires:      -1,
+			HTTPOnly:     e.HttpOnly,
+			Secure:       e.Secure,
+			SameSite:     sameSiteName(e.SameSite),
+			PartitionKey: e.PartitionKey,
+		}
+
+		if !e.HostOnly {
+			c.Domain = "." + c.Domain
+		}
+
+		if e.Persistent {
+			c.Expires = float64(e.Expires.Unix()) + float64(e.Expires.Nanosecond())/float64(time.Second)
+		}
+
+		state.Cookies = append(state.Cookies, c)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(state)
+}
+
+func (s *playwrightSerDer) Deserialize(r io.Reader) (map[string]map[string]Entry, error) {
+	var state playwrightStorageState
+
+	if err := json.NewDecoder(r).Decode(&state); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.origins = state.Origins
+	s.mu.Unlock()
+
+	entries := make(map[string]map[string]Entry)
+
+	for i, c := range state.Cookies {
+		e := Entry{
+			Name:         c.Name,
+			Value:        c.Value,
+			Domain:       strings.ToLower(strings.TrimPrefix(c.Domain, ".")),
+			Path:         c.Path,
+			SameSite:     sameSiteAttribute(c.SameSite),
+			Secure:       c.Secure,
+			HttpOnly:     c.HTTPOnly,
+			HostOnly:     !strings.HasPrefix(c.Domain, "."),
+			Partitioned:  c.PartitionKey != "",
+			PartitionKey: c.PartitionKey,
+			Expires:      endOfTime,
+			SeqNum:       uint64(i), //nolint: gosec
+		}
+
+		if c.Expires >= 0 {
+			sec, frac := math.Modf(c.Expires)
+
+			e.Expires = time.Unix(int64(sec), int64(math.Round(frac*float64(time.Second)))).UTC()
+			e.Persistent = true
+		}
+
+		addEntry(entries, e)
+	}
+
+	return entries, nil
+}
+
+// sameSiteName returns the SameSite value used by browsers, "Strict", "Lax" or "None", for the SameSite of an entry.
+func sameSiteName(sameSite string) string {
+	switch sameSite {
+	case "SameSite=Strict":
+		return "Strict"
+
+	case "SameSite=Lax", "SameSite":
+		return "Lax"
+
+	default:
+		return "None"
+	}
+}
+
+// sameSiteAttribute returns the SameSite of an entry for the SameSite value used by browsers.
+func sameSiteAttribute(sameSite string) string {
+	switch sameSite {
+	case "Strict":
+		return "SameSite=Strict"
+
+	case "Lax":
+		return "SameSite=Lax"
+
+	default:
+		return ""
+	}
+}
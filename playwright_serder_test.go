@@ -0,0 +1,126 @@
+package cookiejar_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swaggest/assertjson"
+
+	"go.nhat.io/cookiejar"
+)
+
+func TestPlaywrightSerDer_Deserialize(t *testing.T) {
+	t.Parallel()
+
+	f, err := os.Open("testdata/storageState.json")
+	require.NoError(t, err)
+
+	defer f.Close() //nolint: errcheck
+
+	actual, err := cookiejar.NewPlaywrightSerDer().Deserialize(f)
+	require.NoError(t, err)
+
+	expected := map[string]map[string]cookiejar.Entry{
+		"example.com": {
+			"www.example.com;/;session": {
+				Name:     "session",
+				Value:    "abc",
+				Domain:   "www.example.com",
+				Path:     "/",
+				SameSite: "SameSite=Strict",
+				Secure:   true,
+				HttpOnly: true,
+				HostOnly: true,
+				Expires:  time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC),
+				SeqNum:   0,
+			},
+			"example.com;/app;theme": {
+				Name:       "theme",
+				Value:      "dark",
+				Domain:     "example.com",
+				Path:       "/app",
+				SameSite:   "SameSite=Lax",
+				Persistent: true,
+				Expires:    time.Date(2100, 1, 1, 0, 0, 0, 500000000, time.UTC),
+				SeqNum:     1,
+			},
+		},
+		"example.net": {
+			"widget.example.net;/;tracking": {
+				Name:         "tracking",
+				Value:        "1",
+				Domain:       "widget.example.net",
+				Path:         "/",
+				Secure:       true,
+				Persistent:   true,
+				HostOnly:     true,
+				Partitioned:  true,
+				PartitionKey: "https://example.org",
+				Expires:      time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC),
+				SeqNum:       2,
+			},
+		},
+	}
+
+	assert.Equal(t, expected, actual)
+}
+
+func TestPlaywrightSerDer_Deserialize_Error(t *testing.T) {
+	t.Parallel()
+
+	actual, err := cookiejar.NewPlaywrightSerDer().Deserialize(bytes.NewReader([]byte(`{"cookies": {}}`)))
+
+	assert.Nil(t, actual)
+	assert.EqualError(t, err, "json: cannot unmarshal object into Go struct field playwrightStorageState.cookies of type []cookiejar.playwrightCookie")
+}
+
+func TestPlaywrightSerDer_Serialize_NoOrigins(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+
+	err := cookiejar.NewPlaywrightSerDer().Serialize(&buf, nil)
+	require.NoError(t, err)
+
+	assertjson.Equal(t, []byte(`{"cookies": [], "origins": []}`), buf.Bytes())
+}
+
+func TestPersistentJar_PlaywrightSerDer_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	const filePath = "/tmp/storageState.json"
+
+	fixture, err := os.ReadFile("testdata/storageState.json")
+	require.NoError(t, err)
+
+	fs := afero.NewMemMapFs()
+
+	require.NoError(t, afero.WriteFile(fs, filePath, fixture, 0o600))
+
+	j := cookiejar.NewPersistentJar(
+		cookiejar.WithFs(fs),
+		cookiejar.WithFilePath(filePath),
+		cookiejar.WithSerDer(cookiejar.NewPlaywrightSerDer()),
+	)
+
+	actual := j.Cookies(&url.URL{Scheme: "https", Host: "www.example.com", Path: "/app/"})
+	expected := []*http.Cookie{
+		{Name: "theme", Value: "dark"},
+		{Name: "session", Value: "abc"},
+	}
+
+	assert.Equal(t, expected, actual)
+	require.NoError(t, j.Sync())
+
+	synced, err := afero.ReadFile(fs, filePath)
+	require.NoError(t, err)
+
+	assertjson.Equal(t, fixture, synced)
+}
@@ -0,0 +1,521 @@
+package cookiejar
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/spf13/afero"
+)
+
+const (
+	// profileJarFile is the file of the jar in the directory of a profile. It is a directory with WithSiteFiles.
+	profileJarFile = "cookies"
+	// profileLockFile is the lock file in the directory of a profile.
+	profileLockFile = ".lock"
+)
+
+var (
+	// ErrInvalidProfileName indicates that a profile name is empty, starts with a dot or has a character other than
+	// letters, digits, ".", "_", "-", "+" and "@".
+	ErrInvalidProfileName = errors.New("cookiejar: invalid profile name")
+	// ErrProfileNotFound indicates that a profile does not exist.
+	ErrProfileNotFound = errors.New("cookiejar: profile not found")
+	// ErrProfileExists indicates that a profile already exists.
+	ErrProfileExists = errors.New("cookiejar: profile already exists")
+	// ErrProfileInUse indicates that a profile is open and cannot be renamed or deleted.
+	ErrProfileInUse = errors.New("cookiejar: profile in use")
+	// ErrProfileLocked indicates that a profile is locked, see Profiles.Lock.
+	ErrProfileLocked = errors.New("cookiejar: profile locked")
+)
+
+// Profiles manages named persistent jars in a directory, e.g. one per account. Each profile is a directory with the
+// file of its jar, and every jar is created with the same options.
+//
+// A profile is opened once, Open returns the same jar until the profile or the profiles are closed. Close persists the
+// open jars and closes their files.
+type Profiles struct {
+	fs   afero.Fs
+	dir  string
+	opts []PersistentJarOption
+
+	mu     sync.Mutex
+	open   map[string]*PersistentJar
+	locked map[string]bool
+}
+
+// NewProfiles returns the profiles in dir on fs. The options are applied to the jar of every profile, before the file
+// system and the file path of the profile, e.g. WithSerDer, WithPublicSuffixList or WithLogger.
+func NewProfiles(fs afero.Fs, dir string, opts ...PersistentJarOption) *Profiles {
+	return &Profiles{
+		fs:     fs,
+		dir:    filepath.Clean(dir),
+		opts:   opts,
+		open:   make(map[string]*PersistentJar),
+		locked: make(map[string]bool),
+	}
+}
+
+// Create creates the profile name and opens its jar.
+func (p *Profiles) Create(name string) (*PersistentJar, error) {
+	if err := validateProfileName(name); err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.mustNotBeLocked(name); err != nil {
+		return nil, err
+	}
+
+	if err := p.create(name); err != nil {
+		return nil, err
+	}
+
+	return p.openJar(name), nil
+}
+
+// Open opens the jar of the profile name. A profile that is locked by someone else cannot be opened, but Open does not
+// lock the profile, see Lock.
+func (p *Profiles) Open(name string) (*PersistentJar, error) {
+	if err := validateProfileName(name); err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.mustExist(name); err != nil {
+		return nil, err
+	}
+
+	if err := p.mustNotBeLocked(name); err != nil {
+		return nil, err
+	}
+
+	return p.openJar(name), nil
+}
+
+// List returns the names of the profiles, sorted.
+func (p *Profiles) List() ([]string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	files, err := afero.ReadDir(p.fs, p.dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("could not list profiles: %w", err)
+	}
+
+	names := make([]string, 0, len(files))
+
+	for _, f := range files {
+		if f.IsDir() && validateProfileName(f.Name()) == nil {
+			names = append(names, f.Name())
+		}
+	}
+
+	return names, nil
+}
+
+// Rename renames the profile from to to. A profile that is open or locked cannot be renamed.
+func (p *Profiles) Rename(from, to string) error {
+	if err := errors.Join(validateProfileName(from), validateProfileName(to)); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.mustBeFree(from, false); err != nil {
+		return err
+	}
+
+	if err := p.mustNotExist(to); err != nil {
+		return err
+	}
+
+	if err := p.fs.Rename(p.path(from), p.path(to)); err != nil {
+		return fmt.Errorf("could not rename profile %q: %w", from, err)
+	}
+
+	return nil
+}
+
+// Copy copies the profile from to the new profile to. An open profile is persisted before it is copied, its lock is
+// not copied.
+func (p *Profiles) Copy(from, to string) error {
+	if err := errors.Join(validateProfileName(from), validateProfileName(to)); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.mustExist(from); err != nil {
+		return err
+	}
+
+	if jar, ok := p.open[from]; ok {
+		if err := jar.Sync(); err != nil {
+			return err
+		}
+	}
+
+	if err := p.create(to); err != nil {
+		return err
+	}
+
+	src, dst := p.path(from), p.path(to)
+
+	err := afero.Walk(p.fs, src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil || rel == "." || rel == profileLockFile {
+			return err
+		}
+
+		if info.IsDir() {
+			return p.fs.MkdirAll(filepath.Join(dst, rel), 0o700)
+		}
+
+		return p.copyFile(path, filepath.Join(dst, rel), info.Mode().Perm())
+	})
+	if err != nil {
+		_ = p.fs.RemoveAll(dst) //nolint: errcheck
+
+		return fmt.Errorf("could not copy profile %q: %w", from, err)
+	}
+
+	return nil
+}
+
+// Delete deletes the profile name and its cookies. A profile that is open or locked by someone else cannot be deleted.
+func (p *Profiles) Delete(name string) error {
+	if err := validateProfileName(name); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.mustBeFree(name, true); err != nil {
+		return err
+	}
+
+	if err := p.fs.RemoveAll(p.path(name)); err != nil {
+		return fmt.Errorf("could not delete profile %q: %w", name, err)
+	}
+
+	delete(p.locked, name)
+
+	return nil
+}
+
+// Lock locks the profile name for the other processes and Profiles that share the directory, until the returned
+// function is called. It fails with ErrProfileLocked if the profile is already locked.
+//
+// The lock is advisory: Open, Create, Rename and Delete fail on a profile locked by someone else, but Open does not
+// take the lock and a jar that was opened before the profile was locked keeps reading and writing its files. The lock
+// is a file in the directory of the profile with the PID and the host name of its process. A lock left by a process of
+// the same host that is gone, e.g. after a crash, is stale and is taken over, a lock of another host has to be removed
+// by hand.
+func (p *Profiles) Lock(name string) (func() error, error) {
+	if err := validateProfileName(name); err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.mustExist(name); err != nil {
+		return nil, err
+	}
+
+	lockPath := p.lockPath(name)
+
+	f, err := p.fs.OpenFile(lockPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, permReadonly)
+	if errors.Is(err, os.ErrExist) && !p.isLocked(name) {
+		if err := p.fs.Remove(lockPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("could not remove stale lock of profile %q: %w", name, err)
+		}
+
+		f, err = p.fs.OpenFile(lockPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, permReadonly)
+	}
+
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("%w: %s", ErrProfileLocked, name)
+		}
+
+		return nil, fmt.Errorf("could not lock profile %q: %w", name, err)
+	}
+
+	host, _ := os.Hostname() //nolint: errcheck
+
+	_, err = io.WriteString(f, strconv.Itoa(os.Getpid())+" "+host)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		_ = p.fs.Remove(lockPath) //nolint: errcheck
+
+		return nil, fmt.Errorf("could not lock profile %q: %w", name, err)
+	}
+
+	p.locked[name] = true
+
+	return sync.OnceValue(func() error {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		delete(p.locked, name)
+
+		if err := p.fs.Remove(lockPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("could not unlock profile %q: %w", name, err)
+		}
+
+		return nil
+	}), nil
+}
+
+// CloseProfile persists the jar of the profile name, closes its files and forgets it, the next Open reads the profile
+// again. It does nothing if the profile is not open.
+func (p *Profiles) CloseProfile(name string) error {
+	if err := validateProfileName(name); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.closeJar(name)
+}
+
+// Close persists the open jars, closes their files and forgets them, the next Open reads the profile again.
+func (p *Profiles) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var errs []error
+
+	for _, name := range sortedKeys(p.open) {
+		if err := p.closeJar(name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// closeJar closes the jar of name and forgets it, even if it cannot be persisted. The caller must hold p.mu.
+func (p *Profiles) closeJar(name string) error {
+	jar, ok := p.open[name]
+	if !ok {
+		return nil
+	}
+
+	delete(p.open, name)
+
+	if err := jar.Close(); err != nil {
+		return fmt.Errorf("could not persist profile %q: %w", name, err)
+	}
+
+	return nil
+}
+
+// openJar returns the open jar of name, or opens it. The caller must hold p.mu.
+func (p *Profiles) openJar(name string) *PersistentJar {
+	if jar, ok := p.open[name]; ok {
+		return jar
+	}
+
+	opts := make([]PersistentJarOption, 0, len(p.opts)+3)
+	opts = append(opts, p.opts...)
+	opts = append(opts, WithFs(p.fs), WithFilePath(filepath.Join(p.path(name), profileJarFile)),
+		// The options are shared, a serializer/deserializer with a state must not be.
+		persistentJarOptionFunc(func(j *PersistentJar) {
+			j.serder = copySerDer(j.serder)
+		}),
+	)
+
+	jar := NewPersistentJar(opts...)
+	p.open[name] = jar
+
+	return jar
+}
+
+// create creates the directory of name. The caller must hold p.mu.
+func (p *Profiles) create(name string) error {
+	if err := p.mustNotExist(name); err != nil {
+		return err
+	}
+
+	if err := p.fs.MkdirAll(p.path(name), 0o700); err != nil {
+		return fmt.Errorf("could not create profile %q: %w", name, err)
+	}
+
+	return nil
+}
+
+func (p *Profiles) mustExist(name string) error {
+	fi, err := p.fs.Stat(p.path(name))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%w: %s", ErrProfileNotFound, name)
+		}
+
+		return err
+	}
+
+	if !fi.IsDir() {
+		return fmt.Errorf("%w: %s", ErrProfileNotFound, name)
+	}
+
+	return nil
+}
+
+func (p *Profiles) mustNotExist(name string) error {
+	_, err := p.fs.Stat(p.path(name))
+	if err == nil {
+		return fmt.Errorf("%w: %s", ErrProfileExists, name)
+	}
+
+	if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// mustBeFree checks that name exists, is not open and is not locked, except by p if ownLock is true. The caller must
+// hold p.mu.
+func (p *Profiles) mustBeFree(name string, ownLock bool) error {
+	if err := p.mustExist(name); err != nil {
+		return err
+	}
+
+	if _, ok := p.open[name]; ok {
+		return fmt.Errorf("%w: %s", ErrProfileInUse, name)
+	}
+
+	if ownLock {
+		return p.mustNotBeLocked(name)
+	}
+
+	if p.isLocked(name) {
+		return fmt.Errorf("%w: %s", ErrProfileLocked, name)
+	}
+
+	return nil
+}
+
+// mustNotBeLocked checks that name is not locked, except by p. The caller must hold p.mu.
+func (p *Profiles) mustNotBeLocked(name string) error {
+	if p.locked[name] {
+		return nil
+	}
+
+	if p.isLocked(name) {
+		return fmt.Errorf("%w: %s", ErrProfileLocked, name)
+	}
+
+	return nil
+}
+
+// isLocked reports whether name has a lock file that is not stale. A lock file that cannot be read is held. The caller
+// must hold p.mu.
+func (p *Profiles) isLocked(name string) bool {
+	data, err := afero.ReadFile(p.fs, p.lockPath(name))
+	if err != nil {
+		return !errors.Is(err, os.ErrNotExist)
+	}
+
+	return !staleLock(string(data))
+}
+
+func (p *Profiles) copyFile(src, dst string, perm os.FileMode) error {
+	in, err := p.fs.Open(src)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = in.Close() //nolint: errcheck
+	}()
+
+	out, err := p.fs.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+func (p *Profiles) path(name string) string {
+	return filepath.Join(p.dir, name)
+}
+
+func (p *Profiles) lockPath(name string) string {
+	return filepath.Join(p.path(name), profileLockFile)
+}
+
+// staleLock reports whether the lock with content was taken by a process of this host that is gone. The lock of
+// another host, or that is being written, is not stale.
+func staleLock(content string) bool {
+	fields := strings.Fields(content)
+	if len(fields) != 2 {
+		return false
+	}
+
+	pid, err := strconv.Atoi(fields[0])
+	if err != nil || pid <= 0 {
+		return false
+	}
+
+	host, err := os.Hostname()
+	if err != nil || host != fields[1] {
+		return false
+	}
+
+	return !processAlive(pid)
+}
+
+func validateProfileName(name string) error {
+	if name == "" || name[0] == '.' {
+		return fmt.Errorf("%w: %q", ErrInvalidProfileName, name)
+	}
+
+	for _, c := range name {
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' {
+			continue
+		}
+
+		switch c {
+		case '.', '_', '-', '+', '@':
+			continue
+		}
+
+		return fmt.Errorf("%w: %q", ErrInvalidProfileName, name)
+	}
+
+	return nil
+}
@@ -0,0 +1,317 @@
+package cookiejar_test
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.nhat.io/cookiejar"
+)
+
+func TestProfiles(t *testing.T) {
+	t.Parallel()
+
+	const dir = "/tmp/profiles"
+
+	fs := afero.NewMemMapFs()
+	u := &url.URL{Scheme: "https", Host: "example.com", Path: "/"}
+
+	profiles := cookiejar.NewProfiles(fs, dir, cookiejar.WithSerDer(cookiejar.NewJSONLinesSerDer()))
+
+	names, err := profiles.List()
+	require.NoError(t, err)
+	assert.Empty(t, names)
+
+	alice, err := profiles.Create("alice")
+	require.NoError(t, err)
+
+	alice.SetCookies(u, []*http.Cookie{{Name: "id", Value: "alice"}})
+
+	bob, err := profiles.Create("bob@example.com")
+	require.NoError(t, err)
+
+	bob.SetCookies(u, []*http.Cookie{{Name: "id", Value: "bob"}})
+
+	_, err = profiles.Create("alice")
+	require.ErrorIs(t, err, cookiejar.ErrProfileExists)
+
+	_, err = profiles.Create("../alice")
+	require.ErrorIs(t, err, cookiejar.ErrInvalidProfileName)
+
+	_, err = profiles.Open("carol")
+	require.ErrorIs(t, err, cookiejar.ErrProfileNotFound)
+
+	// The same jar is returned until the profiles are closed.
+	opened, err := profiles.Open("alice")
+	require.NoError(t, err)
+	assert.Same(t, alice, opened)
+
+	// An open profile is persisted before it is copied.
+	require.NoError(t, profiles.Copy("alice", "carol"))
+
+	// An open profile cannot be renamed or deleted.
+	require.ErrorIs(t, profiles.Rename("alice", "dave"), cookiejar.ErrProfileInUse)
+	require.ErrorIs(t, profiles.Delete("alice"), cookiejar.ErrProfileInUse)
+
+	require.NoError(t, profiles.Close())
+
+	// The jars are persisted with the shared options.
+	data, err := afero.ReadFile(fs, dir+"/bob@example.com/cookies")
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"Value":"bob"`)
+
+	require.NoError(t, profiles.Rename("alice", "dave"))
+	require.ErrorIs(t, profiles.Rename("carol", "dave"), cookiejar.ErrProfileExists)
+	require.NoError(t, profiles.Delete("bob@example.com"))
+
+	names, err = profiles.List()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"carol", "dave"}, names)
+
+	for _, name := range names {
+		jar, err := profiles.Open(name)
+		require.NoError(t, err)
+
+		assert.Equal(t, []*http.Cookie{{Name: "id", Value: "alice"}}, jar.Cookies(u))
+	}
+}
+
+func TestProfiles_Lock(t *testing.T) {
+	t.Parallel()
+
+	const dir = "/tmp/profiles"
+
+	fs := afero.NewMemMapFs()
+
+	profiles := cookiejar.NewProfiles(fs, dir)
+	other := cookiejar.NewProfiles(fs, dir)
+
+	_, err := profiles.Lock("alice")
+	require.ErrorIs(t, err, cookiejar.ErrProfileNotFound)
+
+	_, err = profiles.Create("alice")
+	require.NoError(t, err)
+	require.NoError(t, profiles.Close())
+
+	unlock, err := profiles.Lock("alice")
+	require.NoError(t, err)
+
+	_, err = other.Lock("alice")
+	require.ErrorIs(t, err, cookiejar.ErrProfileLocked)
+
+	// Only the owner of the lock opens the profile.
+	_, err = other.Open("alice")
+	require.ErrorIs(t, err, cookiejar.ErrProfileLocked)
+
+	_, err = profiles.Open("alice")
+	require.NoError(t, err)
+	require.NoError(t, profiles.CloseProfile("alice"))
+
+	require.ErrorIs(t, other.Rename("alice", "bob"), cookiejar.ErrProfileLocked)
+	require.ErrorIs(t, other.Delete("alice"), cookiejar.ErrProfileLocked)
+
+	// The lock is not copied.
+	require.NoError(t, other.Copy("alice", "carol"))
+
+	unlockCarol, err := other.Lock("carol")
+	require.NoError(t, err)
+	require.NoError(t, unlockCarol())
+
+	// A locked profile cannot be renamed by the owner of the lock either, but it can be deleted.
+	require.ErrorIs(t, profiles.Rename("alice", "bob"), cookiejar.ErrProfileLocked)
+
+	require.NoError(t, unlock())
+	require.NoError(t, unlock())
+
+	unlock, err = other.Lock("alice")
+	require.NoError(t, err)
+
+	require.NoError(t, other.Delete("alice"))
+	require.NoError(t, unlock())
+
+	names, err := profiles.List()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"carol"}, names)
+}
+
+func TestProfiles_Lock_Stale(t *testing.T) {
+	t.Parallel()
+
+	const lockPath = "/tmp/profiles/alice/.lock"
+
+	host, err := os.Hostname()
+	require.NoError(t, err)
+
+	// The PID of a process that is gone.
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	require.NoError(t, cmd.Run())
+
+	gone := strconv.Itoa(cmd.Process.Pid)
+
+	testCases := []struct {
+		scenario string
+		lock     string
+		locked   bool
+	}{
+		{
+			scenario: "running process",
+			lock:     strconv.Itoa(os.Getpid()) + " " + host,
+			locked:   true,
+		},
+		{
+			scenario: "process of another host",
+			lock:     gone + " " + host + ".other",
+			locked:   true,
+		},
+		{
+			scenario: "lock being written",
+			locked:   true,
+		},
+		{
+			scenario: "process that is gone",
+			lock:     gone + " " + host,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			fs := afero.NewMemMapFs()
+			profiles := cookiejar.NewProfiles(fs, "/tmp/profiles")
+
+			_, err := profiles.Create("alice")
+			require.NoError(t, err)
+			require.NoError(t, profiles.Close())
+
+			require.NoError(t, afero.WriteFile(fs, lockPath, []byte(tc.lock), 0o600))
+
+			_, err = profiles.Open("alice")
+
+			if !tc.locked {
+				require.NoError(t, err)
+				require.NoError(t, profiles.Close())
+
+				// The stale lock is taken over.
+				unlock, err := profiles.Lock("alice")
+				require.NoError(t, err)
+
+				lock, err := afero.ReadFile(fs, lockPath)
+				require.NoError(t, err)
+				assert.Equal(t, strconv.Itoa(os.Getpid())+" "+host, string(lock))
+
+				require.NoError(t, unlock())
+
+				return
+			}
+
+			require.ErrorIs(t, err, cookiejar.ErrProfileLocked)
+
+			_, err = profiles.Lock("alice")
+			require.ErrorIs(t, err, cookiejar.ErrProfileLocked)
+		})
+	}
+}
+
+func TestProfiles_Reopen(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario string
+		opts     []cookiejar.PersistentJarOption
+	}{
+		{scenario: "file"},
+		{scenario: "journal", opts: []cookiejar.PersistentJarOption{cookiejar.WithJournal(1<<20, 10)}},
+		{scenario: "site files", opts: []cookiejar.PersistentJarOption{cookiejar.WithSiteFiles()}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			const dir = "/tmp/profiles"
+
+			fs := afero.NewMemMapFs()
+			u := &url.URL{Scheme: "https", Host: "example.com", Path: "/"}
+			expected := []*http.Cookie{{Name: "id", Value: "alice"}}
+
+			profiles := cookiejar.NewProfiles(fs, dir, tc.opts...)
+
+			alice, err := profiles.Create("alice")
+			require.NoError(t, err)
+
+			alice.SetCookies(u, []*http.Cookie{{Name: "id", Value: "alice", MaxAge: 3600}})
+
+			require.NoError(t, profiles.CloseProfile("alice"))
+
+			// A profile that is opened and closed without being used keeps its cookies.
+			_, err = profiles.Open("alice")
+			require.NoError(t, err)
+			require.NoError(t, profiles.Close())
+
+			_, err = profiles.Open("alice")
+			require.NoError(t, err)
+			require.NoError(t, profiles.Copy("alice", "bob"))
+
+			for _, name := range []string{"alice", "bob"} {
+				require.NoError(t, profiles.CloseProfile(name))
+
+				jar, err := profiles.Open(name)
+				require.NoError(t, err)
+
+				assert.Equal(t, expected, jar.Cookies(u), name)
+			}
+
+			require.NoError(t, profiles.Close())
+		})
+	}
+}
+
+func TestProfiles_SerDerState(t *testing.T) {
+	t.Parallel()
+
+	const dir = "/tmp/profiles"
+
+	fs := afero.NewMemMapFs()
+	u := &url.URL{Scheme: "https", Host: "example.com", Path: "/"}
+
+	profiles := cookiejar.NewProfiles(fs, dir, cookiejar.WithSerDer(cookiejar.NewPlaywrightSerDer()))
+
+	_, err := profiles.Create("alice")
+	require.NoError(t, err)
+
+	bob, err := profiles.Create("bob")
+	require.NoError(t, err)
+	require.NoError(t, profiles.Close())
+
+	state := `{"cookies": [], "origins": [{"origin": "https://example.com", "localStorage": []}]}`
+
+	require.NoError(t, afero.WriteFile(fs, dir+"/alice/cookies", []byte(state), 0o600))
+
+	alice, err := profiles.Open("alice")
+	require.NoError(t, err)
+	assert.Empty(t, alice.Cookies(u))
+
+	bob, err = profiles.Open("bob")
+	require.NoError(t, err)
+
+	bob.SetCookies(u, []*http.Cookie{{Name: "id", Value: "bob"}})
+
+	require.NoError(t, profiles.Close())
+
+	// The origins of alice are not written to the profile of bob.
+	data, err := afero.ReadFile(fs, dir+"/bob/cookies")
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"origins": []`)
+
+	data, err = afero.ReadFile(fs, dir+"/alice/cookies")
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"origin": "https://example.com"`)
+}
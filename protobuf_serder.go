@@ -0,0 +1,401 @@
+package cookiejar
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The field numbers of proto/cookiejar.proto.
+const (
+	protoJarSites = 1
+
+	protoSiteEntries = 1
+
+	protoMapKey   = 1
+	protoMapValue = 2
+
+	protoEntryName         = 1
+	protoEntryValue        = 2
+	protoEntryQuoted       = 3
+	protoEntryDomain       = 4
+	protoEntryPath         = 5
+	protoEntrySameSite     = 6
+	protoEntrySecure       = 7
+	protoEntryHTTPOnly     = 8
+	protoEntryPersistent   = 9
+	protoEntryHostOnly     = 10
+	protoEntryPartitioned  = 11
+	protoEntryPartitionKey = 12
+	protoEntryExpires      = 13
+	protoEntryCreation     = 14
+	protoEntryLastAccess   = 15
+	protoEntrySeqNum       = 16
+
+	protoTimestampSeconds = 1
+	protoTimestampNanos   = 2
+)
+
+var _ EntrySerDer = (*protobufSerDer)(nil)
+
+// protobufSerDer is a serializer and deserializer for the protobuf schema in proto/cookiejar.proto. A file is a Jar
+// message.
+//
+// The messages are encoded with protowire, without generated code, see the package doc. The field numbers must be kept
+// in sync with the schema, TestProtobufSerDer_Schema compiles it and fails if they are not. The maps are written in the
+// order of their keys, so the output is deterministic, and the unknown fields are skipped when reading.
+type protobufSerDer struct{}
+
+// NewProtobufSerDer returns a serializer/deserializer for the protobuf schema in proto/cookiejar.proto, so that the
+// files can be read in other languages.
+func NewProtobufSerDer() EntrySerDer {
+	return protobufSerDer{}
+}
+
+func (protobufSerDer) Serialize(w io.Writer, entries map[string]map[string]Entry) error {
+	var b []byte
+
+	for _, key := range sortedKeys(entries) {
+		b = protowire.AppendTag(b, protoJarSites, protowire.BytesType)
+		b = protowire.AppendBytes(b, appendProtoSite(nil, key, entries[key]))
+	}
+
+	_, err := w.Write(b)
+
+	return err
+}
+
+func (protobufSerDer) Deserialize(r io.Reader) (map[string]map[string]Entry, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make(map[string]map[string]Entry)
+
+	err = consumeProtoFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if num != protoJarSites || typ != protowire.BytesType {
+			return skipProtoField(num, typ, b)
+		}
+
+		v, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return n, nil
+		}
+
+		key, site, err := consumeProtoSite(v)
+		if err != nil {
+			return 0, err
+		}
+
+		entries[key] = site
+
+		return n, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not decode protobuf: %w", err)
+	}
+
+	return entries, nil
+}
+
+// appendProtoSite appends the map entry of a site, with the key and the Site message, to b.
+func appendProtoSite(b []byte, key string, entries map[string]Entry) []byte {
+	var site []byte
+
+	for _, id := range sortedKeys(entries) {
+		var kv []byte
+
+		kv = protowire.AppendTag(kv, protoMapKey, protowire.BytesType)
+		kv = protowire.AppendString(kv, id)
+		kv = protowire.AppendTag(kv, protoMapValue, protowire.BytesType)
+		kv = protowire.AppendBytes(kv, appendProtoEntry(nil, entries[id]))
+
+		site = protowire.AppendTag(site, protoSiteEntries, protowire.BytesType)
+		site = protowire.AppendBytes(site, kv)
+	}
+
+	b = protowire.AppendTag(b, protoMapKey, protowire.BytesType)
+	b = protowire.AppendString(b, key)
+	b = protowire.AppendTag(b, protoMapValue, protowire.BytesType)
+	b = protowire.AppendBytes(b, site)
+
+	return b
+}
+
+// appendProtoEntry appends the Entry message of e to b. As in proto3, the fields with a zero value are not written.
+func appendProtoEntry(b []byte, e Entry) []byte {
+	appendString := func(num protowire.Number, v string) {
+		if v != "" {
+			b = protowire.AppendTag(b, num, protowire.BytesType)
+			b = protowire.AppendString(b, v)
+		}
+	}
+
+	appendBool := func(num protowire.Number, v bool) {
+		if v {
+			b = protowire.AppendTag(b, num, protowire.VarintType)
+			b = protowire.AppendVarint(b, protowire.EncodeBool(v))
+		}
+	}
+
+	appendTime := func(num protowire.Number, v time.Time) {
+		if v.IsZero() {
+			return
+		}
+
+		var ts []byte
+
+		if s := v.Unix(); s != 0 {
+			ts = protowire.AppendTag(ts, protoTimestampSeconds, protowire.VarintType)
+			ts = protowire.AppendVarint(ts, uint64(s)) //nolint: gosec
+		}
+
+		if ns := v.Nanosecond(); ns != 0 {
+			ts = protowire.AppendTag(ts, protoTimestampNanos, protowire.VarintType)
+			ts = protowire.AppendVarint(ts, uint64(ns))
+		}
+
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		b = protowire.AppendBytes(b, ts)
+	}
+
+	appendString(protoEntryName, e.Name)
+	appendString(protoEntryValue, e.Value)
+	appendBool(protoEntryQuoted, e.Quoted)
+	appendString(protoEntryDomain, e.Domain)
+	appendString(protoEntryPath, e.Path)
+	appendString(protoEntrySameSite, e.SameSite)
+	appendBool(protoEntrySecure, e.Secure)
+	appendBool(protoEntryHTTPOnly, e.HttpOnly)
+	appendBool(protoEntryPersistent, e.Persistent)
+	appendBool(protoEntryHostOnly, e.HostOnly)
+	appendBool(protoEntryPartitioned, e.Partitioned)
+	appendString(protoEntryPartitionKey, e.PartitionKey)
+	appendTime(protoEntryExpires, e.Expires)
+	appendTime(protoEntryCreation, e.Creation)
+	appendTime(protoEntryLastAccess, e.LastAccess)
+
+	if e.SeqNum != 0 {
+		b = protowire.AppendTag(b, protoEntrySeqNum, protowire.VarintType)
+		b = protowire.AppendVarint(b, e.SeqNum)
+	}
+
+	return b
+}
+
+// consumeProtoSite decodes the map entry of a site.
+func consumeProtoSite(b []byte) (string, map[string]Entry, error) {
+	var key string
+
+	entries := make(map[string]Entry)
+
+	err := consumeProtoFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == protoMapKey && typ == protowire.BytesType:
+			v, n := protowire.ConsumeString(b)
+			key = v
+
+			return n, nil
+
+		case num == protoMapValue && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return n, nil
+			}
+
+			return n, consumeProtoFields(v, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+				if num != protoSiteEntries || typ != protowire.BytesType {
+					return skipProtoField(num, typ, b)
+				}
+
+				kv, n := protowire.ConsumeBytes(b)
+				if n < 0 {
+					return n, nil
+				}
+
+				id, e, err := consumeProtoEntry(kv)
+				if err != nil {
+					return 0, err
+				}
+
+				entries[id] = e
+
+				return n, nil
+			})
+
+		default:
+			return skipProtoField(num, typ, b)
+		}
+	})
+
+	return key, entries, err
+}
+
+// consumeProtoEntry decodes the map entry of an Entry message.
+func consumeProtoEntry(b []byte) (string, Entry, error) {
+	var (
+		id string
+		e  Entry
+	)
+
+	err := consumeProtoFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == protoMapKey && typ == protowire.BytesType:
+			v, n := protowire.ConsumeString(b)
+			id = v
+
+			return n, nil
+
+		case num == protoMapValue && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return n, nil
+			}
+
+			return n, consumeProtoFields(v, e.consumeProtoField)
+
+		default:
+			return skipProtoField(num, typ, b)
+		}
+	})
+
+	return id, e, err
+}
+
+// consumeProtoField decodes a field of the Entry message into e.
+func (e *Entry) consumeProtoField(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+	var (
+		str  *string
+		flag *bool
+		tm   *time.Time
+	)
+
+	switch num {
+	case protoEntryName:
+		str = &e.Name
+	case protoEntryValue:
+		str = &e.Value
+	case protoEntryQuoted:
+		flag = &e.Quoted
+	case protoEntryDomain:
+		str = &e.Domain
+	case protoEntryPath:
+		str = &e.Path
+	case protoEntrySameSite:
+		str = &e.SameSite
+	case protoEntrySecure:
+		flag = &e.Secure
+	case protoEntryHTTPOnly:
+		flag = &e.HttpOnly
+	case protoEntryPersistent:
+		flag = &e.Persistent
+	case protoEntryHostOnly:
+		flag = &e.HostOnly
+	case protoEntryPartitioned:
+		flag = &e.Partitioned
+	case protoEntryPartitionKey:
+		str = &e.PartitionKey
+	case protoEntryExpires:
+		tm = &e.Expires
+	case protoEntryCreation:
+		tm = &e.Creation
+	case protoEntryLastAccess:
+		tm = &e.LastAccess
+	case protoEntrySeqNum:
+		if typ == protowire.VarintType {
+			v, n := protowire.ConsumeVarint(b)
+			e.SeqNum = v
+
+			return n, nil
+		}
+	}
+
+	switch {
+	case str != nil && typ == protowire.BytesType:
+		v, n := protowire.ConsumeString(b)
+		*str = v
+
+		return n, nil
+
+	case flag != nil && typ == protowire.VarintType:
+		v, n := protowire.ConsumeVarint(b)
+		*flag = protowire.DecodeBool(v)
+
+		return n, nil
+
+	case tm != nil && typ == protowire.BytesType:
+		v, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return n, nil
+		}
+
+		t, err := consumeProtoTimestamp(v)
+		*tm = t
+
+		return n, err
+
+	default:
+		return skipProtoField(num, typ, b)
+	}
+}
+
+// consumeProtoTimestamp decodes a google.protobuf.Timestamp message as a UTC time.
+func consumeProtoTimestamp(b []byte) (time.Time, error) {
+	var sec, nsec int64
+
+	err := consumeProtoFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if typ != protowire.VarintType || num != protoTimestampSeconds && num != protoTimestampNanos {
+			return skipProtoField(num, typ, b)
+		}
+
+		v, n := protowire.ConsumeVarint(b)
+
+		if num == protoTimestampSeconds {
+			sec = int64(v) //nolint: gosec
+		} else {
+			nsec = int64(int32(v)) //nolint: gosec
+		}
+
+		return n, nil
+	})
+
+	return time.Unix(sec, nsec).UTC(), err
+}
+
+// consumeProtoFields calls fn with the number, the type and the value of every field in b. fn returns the length of
+// the value it consumed, or a negative protowire error code.
+func consumeProtoFields(b []byte, fn func(num protowire.Number, typ protowire.Type, b []byte) (int, error)) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+
+		b = b[n:]
+
+		n, err := fn(num, typ, b)
+		if err != nil {
+			return err
+		}
+
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+
+		b = b[n:]
+	}
+
+	return nil
+}
+
+func skipProtoField(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+	if typ == protowire.EndGroupType {
+		return 0, errors.New("unexpected end group")
+	}
+
+	return protowire.ConsumeFieldValue(num, typ, b), nil
+}
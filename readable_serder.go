@@ -0,0 +1,216 @@
+package cookiejar
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	_ EntrySerDer = (*yamlSerDer)(nil)
+	_ EntrySerDer = (*prettyJSONSerDer)(nil)
+)
+
+// readableEntry is an entry in the YAML and pretty JSON formats. The zero fields are omitted and the times are RFC 3339
+// strings in UTC.
+type readableEntry struct {
+	Name         string `json:"name,omitempty" yaml:"name,omitempty"`
+	Value        string `json:"value,omitempty" yaml:"value,omitempty"`
+	Quoted       bool   `json:"quoted,omitempty" yaml:"quoted,omitempty"`
+	Domain       string `json:"domain,omitempty" yaml:"domain,omitempty"`
+	Path         string `json:"path,omitempty" yaml:"path,omitempty"`
+	SameSite     string `json:"same_site,omitempty" yaml:"same_site,omitempty"`
+	Secure       bool   `json:"secure,omitempty" yaml:"secure,omitempty"`
+	HttpOnly     bool   `json:"http_only,omitempty" yaml:"http_only,omitempty"`
+	Persistent   bool   `json:"persistent,omitempty" yaml:"persistent,omitempty"`
+	HostOnly     bool   `json:"host_only,omitempty" yaml:"host_only,omitempty"`
+	Partitioned  bool   `json:"partitioned,omitempty" yaml:"partitioned,omitempty"`
+	PartitionKey string `json:"partition_key,omitempty" yaml:"partition_key,omitempty"`
+	Expires      string `json:"expires,omitempty" yaml:"expires,omitempty"`
+	Creation     string `json:"creation,omitempty" yaml:"creation,omitempty"`
+	LastAccess   string `json:"last_access,omitempty" yaml:"last_access,omitempty"`
+	SeqNum       uint64 `json:"seq_num,omitempty" yaml:"seq_num,omitempty"`
+}
+
+// yamlSerDer is a serializer and deserializer for YAML.
+type yamlSerDer struct{}
+
+// NewYAMLSerDer returns a serializer/deserializer for YAML, meant to be edited by hand and kept in version control.
+//
+// The file is a mapping of eTLD+1 to the list of its cookies, sorted by eTLD+1, domain, path and name. The zero fields
+// are omitted and the times are RFC 3339 strings in UTC, so that the output only changes with the cookies. An unknown
+// field is an error.
+func NewYAMLSerDer() EntrySerDer {
+	return yamlSerDer{}
+}
+
+func (yamlSerDer) Serialize(w io.Writer, entries map[string]map[string]Entry) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+
+	if err := enc.Encode(toReadable(entries)); err != nil {
+		return err
+	}
+
+	return enc.Close()
+}
+
+func (yamlSerDer) Deserialize(r io.Reader) (map[string]map[string]Entry, error) {
+	var doc map[string][]readableEntry
+
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+
+	if err := dec.Decode(&doc); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	return fromReadable(doc)
+}
+
+// prettyJSONSerDer is a serializer and deserializer for indented JSON.
+type prettyJSONSerDer struct{}
+
+// NewPrettyJSONSerDer returns a serializer/deserializer for indented JSON, meant to be edited by hand and kept in
+// version control.
+//
+// The file has the same layout as NewYAMLSerDer: an object of eTLD+1 to the array of its cookies, sorted by eTLD+1,
+// domain, path and name, without zero fields and with RFC 3339 times in UTC. An unknown field is an error.
+func NewPrettyJSONSerDer() EntrySerDer {
+	return prettyJSONSerDer{}
+}
+
+func (prettyJSONSerDer) Serialize(w io.Writer, entries map[string]map[string]Entry) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(toReadable(entries))
+}
+
+func (prettyJSONSerDer) Deserialize(r io.Reader) (map[string]map[string]Entry, error) {
+	var doc map[string][]readableEntry
+
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	return fromReadable(doc)
+}
+
+// toReadable converts entries to the readable layout. The keys of the map are sorted by the encoders.
+func toReadable(entries map[string]map[string]Entry) map[string][]readableEntry {
+	doc := make(map[string][]readableEntry, len(entries))
+
+	for key, submap := range entries {
+		sorted := slices.SortedFunc(maps.Values(submap), func(a, b Entry) int {
+			return cmp.Or(
+				cmp.Compare(a.Domain, b.Domain),
+				cmp.Compare(a.Path, b.Path),
+				cmp.Compare(a.Name, b.Name),
+			)
+		})
+
+		list := make([]readableEntry, 0, len(sorted))
+
+		for _, e := range sorted {
+			list = append(list, readableEntry{
+				Name:         e.Name,
+				Value:        e.Value,
+				Quoted:       e.Quoted,
+				Domain:       e.Domain,
+				Path:         e.Path,
+				SameSite:     e.SameSite,
+				Secure:       e.Secure,
+				HttpOnly:     e.HttpOnly,
+				Persistent:   e.Persistent,
+				HostOnly:     e.HostOnly,
+				Partitioned:  e.Partitioned,
+				PartitionKey: e.PartitionKey,
+				Expires:      formatReadableTime(e.Expires),
+				Creation:     formatReadableTime(e.Creation),
+				LastAccess:   formatReadableTime(e.LastAccess),
+				SeqNum:       e.SeqNum,
+			})
+		}
+
+		doc[key] = list
+	}
+
+	return doc
+}
+
+// fromReadable converts the readable layout to entries.
+func fromReadable(doc map[string][]readableEntry) (map[string]map[string]Entry, error) {
+	entries := make(map[string]map[string]Entry, len(doc))
+
+	for key, list := range doc {
+		entries[key] = make(map[string]Entry, len(list))
+
+		for _, r := range list {
+			e := Entry{
+				Name:         r.Name,
+				Value:        r.Value,
+				Quoted:       r.Quoted,
+				Domain:       r.Domain,
+				Path:         r.Path,
+				SameSite:     r.SameSite,
+				Secure:       r.Secure,
+				HttpOnly:     r.HttpOnly,
+				Persistent:   r.Persistent,
+				HostOnly:     r.HostOnly,
+				Partitioned:  r.Partitioned,
+				PartitionKey: r.PartitionKey,
+				SeqNum:       r.SeqNum,
+			}
+
+			var err error
+
+			if e.Expires, err = parseReadableTime(r.Expires); err != nil {
+				return nil, fmt.Errorf("invalid expires of %q: %w", e.ID(), err)
+			}
+
+			if e.Creation, err = parseReadableTime(r.Creation); err != nil {
+				return nil, fmt.Errorf("invalid creation of %q: %w", e.ID(), err)
+			}
+
+			if e.LastAccess, err = parseReadableTime(r.LastAccess); err != nil {
+				return nil, fmt.Errorf("invalid last access of %q: %w", e.ID(), err)
+			}
+
+			entries[key][e.ID()] = e
+		}
+	}
+
+	return entries, nil
+}
+
+func formatReadableTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func parseReadableTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return t.UTC(), nil
+}
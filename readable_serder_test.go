@@ -0,0 +1,200 @@
+package cookiejar_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.nhat.io/cookiejar"
+)
+
+func readableEntries() map[string]map[string]cookiejar.Entry {
+	return map[string]map[string]cookiejar.Entry{
+		"example.org": {
+			"example.org;/;lang": {Name: "lang", Value: "en", Domain: "example.org", Path: "/", HostOnly: true},
+		},
+		"example.com": {
+			"www.example.com;/;theme": {Name: "theme", Value: "dark", Domain: "www.example.com", Path: "/"},
+			"example.com;/;id": {
+				Name:       "id",
+				Value:      "42",
+				Domain:     "example.com",
+				Path:       "/",
+				Secure:     true,
+				HttpOnly:   true,
+				Persistent: true,
+				Expires:    time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC),
+				Creation:   time.Date(2024, 1, 2, 3, 4, 5, 600, time.UTC),
+				SeqNum:     1,
+			},
+		},
+	}
+}
+
+func TestReadableSerDer_Serialize(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario string
+		serder   cookiejar.EntrySerDer
+		expected string
+	}{
+		{
+			scenario: "yaml",
+			serder:   cookiejar.NewYAMLSerDer(),
+			expected: `example.com:
+  - name: id
+    value: "42"
+    domain: example.com
+    path: /
+    secure: true
+    http_only: true
+    persistent: true
+    expires: "2030-01-02T03:04:05Z"
+    creation: "2024-01-02T03:04:05.0000006Z"
+    seq_num: 1
+  - name: theme
+    value: dark
+    domain: www.example.com
+    path: /
+example.org:
+  - name: lang
+    value: en
+    domain: example.org
+    path: /
+    host_only: true
+`,
+		},
+		{
+			scenario: "pretty json",
+			serder:   cookiejar.NewPrettyJSONSerDer(),
+			expected: `{
+  "example.com": [
+    {
+      "name": "id",
+      "value": "42",
+      "domain": "example.com",
+      "path": "/",
+      "secure": true,
+      "http_only": true,
+      "persistent": true,
+      "expires": "2030-01-02T03:04:05Z",
+      "creation": "2024-01-02T03:04:05.0000006Z",
+      "seq_num": 1
+    },
+    {
+      "name": "theme",
+      "value": "dark",
+      "domain": "www.example.com",
+      "path": "/"
+    }
+  ],
+  "example.org": [
+    {
+      "name": "lang",
+      "value": "en",
+      "domain": "example.org",
+      "path": "/",
+      "host_only": true
+    }
+  ]
+}
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+
+			require.NoError(t, tc.serder.Serialize(&buf, readableEntries()))
+
+			assert.Equal(t, tc.expected, buf.String())
+
+			actual, err := tc.serder.Deserialize(&buf)
+			require.NoError(t, err)
+
+			assert.Equal(t, readableEntries(), actual)
+		})
+	}
+}
+
+func TestReadableSerDer_Deserialize(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario      string
+		serder        cookiejar.EntrySerDer
+		data          string
+		expected      map[string]map[string]cookiejar.Entry
+		expectedError string
+	}{
+		{
+			scenario: "yaml with an offset",
+			serder:   cookiejar.NewYAMLSerDer(),
+			data: `example.com:
+  - name: id
+    domain: example.com
+    path: /
+    expires: 2030-01-02T05:04:05+02:00
+`,
+			expected: map[string]map[string]cookiejar.Entry{
+				"example.com": {
+					"example.com;/;id": {
+						Name:    "id",
+						Domain:  "example.com",
+						Path:    "/",
+						Expires: time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC),
+					},
+				},
+			},
+		},
+		{
+			scenario: "empty yaml",
+			serder:   cookiejar.NewYAMLSerDer(),
+			expected: map[string]map[string]cookiejar.Entry{},
+		},
+		{
+			scenario:      "yaml with an unknown field",
+			serder:        cookiejar.NewYAMLSerDer(),
+			data:          "example.com:\n  - name: id\n    hostonly: true\n",
+			expectedError: "field hostonly not found",
+		},
+		{
+			scenario:      "json with an unknown field",
+			serder:        cookiejar.NewPrettyJSONSerDer(),
+			data:          `{"example.com": [{"name": "id", "hostonly": true}]}`,
+			expectedError: `unknown field "hostonly"`,
+		},
+		{
+			scenario:      "json with an invalid time",
+			serder:        cookiejar.NewPrettyJSONSerDer(),
+			data:          `{"example.com": [{"name": "id", "domain": "example.com", "path": "/", "expires": "tomorrow"}]}`,
+			expectedError: `invalid expires of "example.com;/;id"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := tc.serder.Deserialize(strings.NewReader(tc.data))
+
+			if tc.expectedError != "" {
+				require.ErrorContains(t, err, tc.expectedError)
+				assert.Nil(t, actual)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package cookiejar
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"slices"
+	"strings"
+)
+
+// RedactedValue is the placeholder of the redacted values in the RedactPlaceholder mode.
+const RedactedValue = "[redacted]"
+
+// RedactMode is how the values are redacted.
+type RedactMode int
+
+const (
+	// RedactHash replaces a value with a keyed hash of the same length, in hexadecimal. The same value has the same hash
+	// in an export, so the cookies that share a value can still be told apart from the others.
+	RedactHash RedactMode = iota
+	// RedactPlaceholder replaces a value with RedactedValue.
+	RedactPlaceholder
+)
+
+// RedactOptions are the options of a redacted export.
+type RedactOptions struct {
+	// Mode is how the values are redacted. The default is RedactHash.
+	Mode RedactMode
+
+	// HashKey is the key of the hashes in the RedactHash mode. A nil key is a random key for every export, so that the
+	// hashes of two exports cannot be compared and a short value cannot be guessed from its hash.
+	HashKey []byte
+
+	// Allow is the allowlist of the values that are safe to keep, by cookie name, e.g. {"lang": nil} or
+	// {"consent": {"yes", "no"}}. An empty list keeps every value of the name.
+	Allow map[string][]string
+
+	// SerDer is the format of the export. A nil value is the default JSON format.
+	SerDer EntrySerDer
+}
+
+// redactor returns a function that redacts the value of an entry with o.
+func (o RedactOptions) redactor() (func(e Entry) Entry, error) {
+	key := o.HashKey
+
+	if o.Mode == RedactHash && key == nil {
+		key = make([]byte, sha256.Size)
+
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(e Entry) Entry {
+		if e.Value == "" {
+			return e
+		}
+
+		if allowed, ok := o.Allow[e.Name]; ok && (len(allowed) == 0 || slices.Contains(allowed, e.Value)) {
+			return e
+		}
+
+		if o.Mode == RedactPlaceholder {
+			e.Value = RedactedValue
+		} else {
+			e.Value = redactHash(key, e.Value)
+		}
+
+		return e
+	}, nil
+}
+
+// redactHash returns the HMAC-SHA256 of value in hexadecimal, extended with a counter or truncated to the length of
+// value.
+func redactHash(key []byte, value string) string {
+	var sb strings.Builder
+
+	for counter := uint32(0); sb.Len() < len(value); counter++ {
+		mac := hmac.New(sha256.New, key)
+
+		_ = binary.Write(mac, binary.BigEndian, counter) //nolint: errcheck
+		_, _ = io.WriteString(mac, value)                //nolint: errcheck
+
+		sb.WriteString(hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	return sb.String()[:len(value)]
+}
+
+// redactEntries returns a copy of entries with the values redacted by redact.
+func redactEntries(entries map[string]map[string]Entry, redact func(e Entry) Entry) map[string]map[string]Entry {
+	redacted := make(map[string]map[string]Entry, len(entries))
+
+	for key, submap := range entries {
+		redacted[key] = make(map[string]Entry, len(submap))
+
+		for id, e := range submap {
+			redacted[key][id] = redact(e)
+		}
+	}
+
+	return redacted
+}
+
+var _ EntrySerDer = (*redactedSerDer)(nil)
+
+// redactedSerDer redacts the values before passing the entries to another serializer.
+type redactedSerDer struct {
+	serder EntrySerDer
+	opts   RedactOptions
+}
+
+// NewRedactedSerDer returns a serializer/deserializer that redacts the values of the cookies with opts before
+// serializing them with serder, e.g. to attach the cookies to a bug report. A nil serder is opts.SerDer, or the default
+// JSON serializer/deserializer. Deserialize reads the redacted values as they are.
+//
+// The serializer/deserializer is for exports only. It must not be the one of a PersistentJar: the values of the file
+// would be replaced with the redacted ones by Sync and lost.
+func NewRedactedSerDer(serder EntrySerDer, opts RedactOptions) EntrySerDer {
+	if serder == nil {
+		serder = opts.SerDer
+	}
+
+	if serder == nil {
+		serder = jsonSerDer{}
+	}
+
+	return &redactedSerDer{
+		serder: serder,
+		opts:   opts,
+	}
+}
+
+func (s *redactedSerDer) copySerDer() EntrySerDer {
+	c := *s
+	c.serder = copySerDer(s.serder)
+
+	return &c
+}
+
+func (s *redactedSerDer) Serialize(w io.Writer, entries map[string]map[string]Entry) error {
+	redact, err := s.opts.redactor()
+	if err != nil {
+		return err
+	}
+
+	return s.serder.Serialize(w, redactEntries(entries, redact))
+}
+
+func (s *redactedSerDer) Deserialize(r io.Reader) (map[string]map[string]Entry, error) {
+	return s.serder.Deserialize(r)
+}
+
+// ExportRedacted writes the entries of the jar to w with the values redacted, see [RedactOptions]. The names, domains,
+// paths, flags and times are kept. The values of the sealed cookies are never exported, they are SealedValue.
+func (j *Jar) ExportRedacted(w io.Writer, opts RedactOptions) error {
+	redact, err := opts.redactor()
+	if err != nil {
+		return err
+	}
+
+	serder := opts.SerDer
+	if serder == nil {
+		serder = jsonSerDer{}
+	}
+
+	entries := make(map[string]map[string]Entry)
+
+	for _, e := range j.Entries() {
+		// The placeholder of a sealed value is kept, its hash would look like a value.
+		if !j.sealer.hides(e) {
+			e = redact(e)
+		}
+
+		key := jarKey(e.Domain, j.psList)
+
+		if entries[key] == nil {
+			entries[key] = make(map[string]Entry)
+		}
+
+		entries[key][e.ID()] = e
+	}
+
+	return serder.Serialize(w, entries)
+}
+
+// ExportRedacted writes the entries of the jar to w with the values redacted, see [Jar.ExportRedacted].
+func (j *PersistentJar) ExportRedacted(w io.Writer, opts RedactOptions) error {
+	j.lazyLoad.Do(j.load)
+
+	return j.jar.ExportRedacted(w, opts)
+}
@@ -0,0 +1,140 @@
+package cookiejar_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.nhat.io/cookiejar"
+)
+
+func TestJar_ExportRedacted(t *testing.T) {
+	t.Parallel()
+
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+
+	u := &url.URL{Scheme: "https", Host: "example.com", Path: "/"}
+
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "session", Value: "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef-long", Secure: true},
+		{Name: "token", Value: "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef-long"},
+		{Name: "lang", Value: "en"},
+		{Name: "consent", Value: "yes"},
+		{Name: "tracking", Value: "abc"},
+		{Name: "empty"},
+	})
+
+	opts := cookiejar.RedactOptions{
+		Allow: map[string][]string{
+			"lang":     nil,
+			"consent":  {"yes", "no"},
+			"tracking": {"off"},
+		},
+	}
+
+	export := func(opts cookiejar.RedactOptions) map[string]cookiejar.Entry {
+		var buf bytes.Buffer
+
+		require.NoError(t, jar.ExportRedacted(&buf, opts))
+
+		var entries map[string]map[string]cookiejar.Entry
+
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &entries))
+
+		return entries["example.com"]
+	}
+
+	actual := export(opts)
+
+	session := actual["example.com;/;session"]
+
+	assert.Len(t, session.Value, 69)
+	assert.NotContains(t, session.Value, "0123456789")
+	assert.Regexp(t, "^[0-9a-f]+$", session.Value)
+	assert.True(t, session.Secure)
+	assert.Equal(t, "/", session.Path)
+
+	// The same value has the same hash in an export.
+	assert.Equal(t, session.Value, actual["example.com;/;token"].Value)
+
+	assert.Equal(t, "en", actual["example.com;/;lang"].Value)
+	assert.Equal(t, "yes", actual["example.com;/;consent"].Value)
+	assert.Len(t, actual["example.com;/;tracking"].Value, 3)
+	assert.NotEqual(t, "abc", actual["example.com;/;tracking"].Value)
+	assert.Empty(t, actual["example.com;/;empty"].Value)
+
+	// The hashes of two exports differ without a key.
+	assert.NotEqual(t, session.Value, export(opts)["example.com;/;session"].Value)
+
+	opts.HashKey = []byte("key")
+
+	assert.Equal(t, export(opts)["example.com;/;session"].Value, export(opts)["example.com;/;session"].Value)
+
+	opts.Mode = cookiejar.RedactPlaceholder
+
+	assert.Equal(t, cookiejar.RedactedValue, export(opts)["example.com;/;session"].Value)
+}
+
+func TestJar_ExportRedacted_Sealed(t *testing.T) {
+	t.Parallel()
+
+	jar, err := cookiejar.New(&cookiejar.Options{
+		Sealer: cookiejar.NewSealer(cookiejar.RawKey(newKey), cookiejar.SealRule{Name: "session"}),
+	})
+	require.NoError(t, err)
+
+	u := &url.URL{Scheme: "https", Host: "example.com", Path: "/"}
+
+	jar.SetCookies(u, []*http.Cookie{{Name: "session", Value: "secret"}, {Name: "token", Value: cookiejar.SealedValue}})
+
+	for _, mode := range []cookiejar.RedactMode{cookiejar.RedactHash, cookiejar.RedactPlaceholder} {
+		var buf bytes.Buffer
+
+		require.NoError(t, jar.ExportRedacted(&buf, cookiejar.RedactOptions{Mode: mode}))
+
+		var entries map[string]map[string]cookiejar.Entry
+
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &entries))
+
+		// The placeholder of the sealed value is kept, the same value of a cookie that is not sealed is redacted.
+		assert.Equal(t, cookiejar.SealedValue, entries["example.com"]["example.com;/;session"].Value)
+		assert.NotEqual(t, cookiejar.SealedValue, entries["example.com"]["example.com;/;token"].Value)
+	}
+}
+
+func TestRedactedSerDer(t *testing.T) {
+	t.Parallel()
+
+	entries := map[string]map[string]cookiejar.Entry{
+		"example.com": {
+			"example.com;/;id":   {Name: "id", Value: "42", Domain: "example.com", Path: "/"},
+			"example.com;/;lang": {Name: "lang", Value: "en", Domain: "example.com", Path: "/"},
+		},
+	}
+
+	s := cookiejar.NewRedactedSerDer(cookiejar.NewNetscapeSerDer(), cookiejar.RedactOptions{
+		Mode:  cookiejar.RedactPlaceholder,
+		Allow: map[string][]string{"lang": nil},
+	})
+
+	var buf bytes.Buffer
+
+	require.NoError(t, s.Serialize(&buf, entries))
+
+	assert.Contains(t, buf.String(), "\tid\t[redacted]\n")
+	assert.Contains(t, buf.String(), "\tlang\ten\n")
+
+	// The entries are not modified.
+	assert.Equal(t, "42", entries["example.com"]["example.com;/;id"].Value)
+
+	actual, err := s.Deserialize(&buf)
+	require.NoError(t, err)
+
+	assert.Equal(t, cookiejar.RedactedValue, actual["example.com"]["example.com;/;id"].Value)
+}
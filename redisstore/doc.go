@@ -0,0 +1,2 @@
+// Package redisstore stores the entries of a cookiejar.Jar in Redis, so that several processes share the same cookies.
+package redisstore
@@ -0,0 +1,42 @@
+package redisstore
+
+import (
+	"github.com/bool64/ctxd"
+
+	"go.nhat.io/cookiejar"
+)
+
+// Option is an option to configure Store.
+type Option interface {
+	applyOption(s *Store)
+}
+
+type optionFunc func(s *Store)
+
+func (f optionFunc) applyOption(s *Store) {
+	f(s)
+}
+
+// WithKeyPrefix sets the prefix of the Redis keys, "cookiejar:" by default. The stores that share the cookies must
+// have the same prefix.
+func WithKeyPrefix(prefix string) Option {
+	return optionFunc(func(s *Store) {
+		s.prefix = prefix
+	})
+}
+
+// WithCache caches the hashes in memory. The updates are published on the "<prefix>invalidate" channel, so that the
+// other stores drop their cached copy.
+func WithCache() Option {
+	return optionFunc(func(s *Store) {
+		s.cache = make(map[string]map[string]cookiejar.Entry)
+		s.generations = make(map[string]uint64)
+	})
+}
+
+// WithLogger sets the logger of the errors that are not returned, e.g. a failed invalidation of the cached hashes.
+func WithLogger(logger ctxd.Logger) Option {
+	return optionFunc(func(s *Store) {
+		s.logger = logger
+	})
+}
@@ -0,0 +1,336 @@
+package redisstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bool64/ctxd"
+	"github.com/redis/go-redis/v9"
+
+	"go.nhat.io/cookiejar"
+)
+
+// maxRetries is the number of times an update is retried when the hash is modified by another process.
+const maxRetries = 10
+
+var _ cookiejar.Store = (*Store)(nil)
+
+// Store is a cookiejar.Store in Redis.
+//
+// Every eTLD+1 is a hash and every entry is a JSON value in the field of its domain;path;name id. The hash expires with
+// its last persistent entry and never expires if it has a session entry. An update watches the hash and writes the
+// entries that changed in a MULTI/EXEC transaction, it is retried if the hash is modified by another process.
+//
+// With WithCache, the hashes are cached in memory and the updates are published, so that every store that shares the
+// Redis drops its cached copy of the hash. An update that only changes the last access time of the entries, as the
+// jar writes them in batches, is not published: the cached copies of the other stores may have an older last access
+// time.
+type Store struct {
+	client  redis.UniversalClient
+	prefix  string
+	channel string
+	pubsub  *redis.PubSub
+	logger  ctxd.Logger
+
+	mu    sync.Mutex
+	cache map[string]map[string]cookiejar.Entry
+	// generations counts the invalidations of every key, a hash read before an invalidation is not cached.
+	generations map[string]uint64
+	done        chan struct{}
+}
+
+// New returns a store in Redis. The store must be closed if the cache is enabled, the client is not closed by the
+// store.
+func New(ctx context.Context, client redis.UniversalClient, opts ...Option) (*Store, error) {
+	s := &Store{
+		client: client,
+		prefix: "cookiejar:",
+		logger: ctxd.NoOpLogger{},
+	}
+
+	for _, opt := range opts {
+		opt.applyOption(s)
+	}
+
+	s.channel = s.prefix + "invalidate"
+
+	if s.cache == nil {
+		return s, nil
+	}
+
+	s.pubsub = client.Subscribe(ctx, s.channel)
+
+	// Wait for the subscription, so that no invalidation is missed after New returns.
+	if _, err := s.pubsub.Receive(ctx); err != nil {
+		_ = s.pubsub.Close() //nolint: errcheck
+
+		return nil, fmt.Errorf("could not subscribe to cookies invalidation: %w", err)
+	}
+
+	s.done = make(chan struct{})
+
+	go s.invalidate(s.pubsub.Channel())
+
+	return s, nil
+}
+
+// Close stops listening to the invalidations.
+func (s *Store) Close() error {
+	if s.pubsub == nil {
+		return nil
+	}
+
+	err := s.pubsub.Close()
+
+	<-s.done
+
+	return err
+}
+
+// Entries returns the entries of key.
+func (s *Store) Entries(key string) (map[string]cookiejar.Entry, error) {
+	entries, gen, ok := s.cached(key)
+	if ok {
+		return entries, nil
+	}
+
+	entries, err := readHash(context.Background(), s.client, s.prefix+key)
+	if err != nil {
+		return nil, fmt.Errorf("could not read cookies of %q: %w", key, err)
+	}
+
+	s.store(key, entries, gen)
+
+	return entries, nil
+}
+
+// All returns the entries of every key.
+func (s *Store) All() (map[string]map[string]cookiejar.Entry, error) {
+	ctx := context.Background()
+	all := make(map[string]map[string]cookiejar.Entry)
+	iter := s.client.Scan(ctx, 0, s.prefix+"*", 0).Iterator()
+
+	for iter.Next(ctx) {
+		name := iter.Val()
+		if name == s.channel {
+			continue
+		}
+
+		entries, err := readHash(ctx, s.client, name)
+		if err != nil {
+			return nil, fmt.Errorf("could not read cookies of %q: %w", name, err)
+		}
+
+		if len(entries) > 0 {
+			all[strings.TrimPrefix(name, s.prefix)] = entries
+		}
+	}
+
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("could not list cookies: %w", err)
+	}
+
+	return all, nil
+}
+
+// Update watches the hash of key, calls fn with its entries and writes the entries that changed in one transaction.
+func (s *Store) Update(key string, fn func(entries map[string]cookiejar.Entry) bool) error {
+	ctx := context.Background()
+	name := s.prefix + key
+
+	for range maxRetries {
+		var (
+			modified, accessed bool
+			after              map[string]cookiejar.Entry
+		)
+
+		_, gen, _ := s.cached(key)
+
+		err := s.client.Watch(ctx, func(tx *redis.Tx) error {
+			before, err := readHash(ctx, tx, name)
+			if err != nil {
+				return err
+			}
+
+			after = maps.Clone(before)
+
+			if modified = fn(after); !modified {
+				return nil
+			}
+
+			upserts, deletes := cookiejar.DiffEntries(before, after)
+			accessed = len(deletes) == 0 && onlyAccessed(before, upserts)
+
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				return writeHash(ctx, pipe, name, after, upserts, deletes)
+			})
+
+			return err
+		}, name)
+
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+
+		if err != nil {
+			return fmt.Errorf("could not update cookies of %q: %w", key, err)
+		}
+
+		if !modified {
+			return nil
+		}
+
+		s.store(key, after, gen)
+
+		// The update is committed, a failed invalidation only leaves the other caches stale.
+		if s.cache != nil && !accessed {
+			if err := s.client.Publish(ctx, s.channel, key).Err(); err != nil {
+				s.logger.Error(ctx, "could not publish cookies invalidation", "cookies.key", key, "error", err)
+			}
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("could not update cookies of %q: %w", key, redis.TxFailedErr)
+}
+
+// cached returns the cached entries of key, if any, and the generation of key.
+func (s *Store) cached(key string) (map[string]cookiejar.Entry, uint64, bool) {
+	if s.cache == nil {
+		return nil, 0, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, ok := s.cache[key]
+
+	return maps.Clone(entries), s.generations[key], ok
+}
+
+// store caches the entries of key read at the generation gen. They are dropped if key was invalidated since then.
+func (s *Store) store(key string, entries map[string]cookiejar.Entry, gen uint64) {
+	if s.cache == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.generations[key] != gen {
+		return
+	}
+
+	s.cache[key] = maps.Clone(entries)
+}
+
+// invalidate drops the cached hashes that are published as modified, until the subscription is closed.
+func (s *Store) invalidate(messages <-chan *redis.Message) {
+	defer close(s.done)
+
+	for msg := range messages {
+		s.mu.Lock()
+		delete(s.cache, msg.Payload)
+		s.generations[msg.Payload]++
+		s.mu.Unlock()
+	}
+}
+
+// onlyAccessed reports whether the upserts only change the last access time of entries that are in before.
+func onlyAccessed(before map[string]cookiejar.Entry, upserts []cookiejar.Entry) bool {
+	for _, e := range upserts {
+		old, ok := before[e.ID()]
+		if !ok {
+			return false
+		}
+
+		if !old.Expires.Equal(e.Expires) || !old.Creation.Equal(e.Creation) {
+			return false
+		}
+
+		old.Expires, old.Creation, old.LastAccess = e.Expires, e.Creation, e.LastAccess
+
+		if old != e {
+			return false
+		}
+	}
+
+	return true
+}
+
+func readHash(ctx context.Context, c redis.Cmdable, name string) (map[string]cookiejar.Entry, error) {
+	fields, err := c.HGetAll(ctx, name).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make(map[string]cookiejar.Entry, len(fields))
+
+	for id, value := range fields {
+		var e cookiejar.Entry
+
+		if err := json.Unmarshal([]byte(value), &e); err != nil {
+			return nil, fmt.Errorf("could not decode cookie %q: %w", id, err)
+		}
+
+		entries[id] = e
+	}
+
+	return entries, nil
+}
+
+// writeHash queues the changes of the hash and sets its expiry to the one of its last persistent entry, or removes
+// the expiry if it has a session entry.
+func writeHash(ctx context.Context, pipe redis.Pipeliner, name string, entries map[string]cookiejar.Entry,
+	upserts, deletes []cookiejar.Entry,
+) error {
+	if len(entries) == 0 {
+		pipe.Del(ctx, name)
+
+		return nil
+	}
+
+	for _, e := range deletes {
+		pipe.HDel(ctx, name, e.ID())
+	}
+
+	if len(upserts) > 0 {
+		values := make([]any, 0, 2*len(upserts))
+
+		for _, e := range upserts {
+			data, err := json.Marshal(e)
+			if err != nil {
+				return err
+			}
+
+			values = append(values, e.ID(), data)
+		}
+
+		pipe.HSet(ctx, name, values...)
+	}
+
+	var expires time.Time
+
+	for _, e := range entries {
+		if !e.Persistent {
+			pipe.Persist(ctx, name)
+
+			return nil
+		}
+
+		if e.Expires.After(expires) {
+			expires = e.Expires
+		}
+	}
+
+	pipe.ExpireAt(ctx, name, expires)
+
+	return nil
+}
@@ -0,0 +1,248 @@
+package redisstore_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/bool64/ctxd"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.nhat.io/cookiejar"
+	"go.nhat.io/cookiejar/redisstore"
+)
+
+func newClient(t *testing.T, mr *miniredis.Miniredis) *redis.Client {
+	t.Helper()
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	t.Cleanup(func() {
+		assert.NoError(t, client.Close())
+	})
+
+	return client
+}
+
+func newStore(t *testing.T, client redis.UniversalClient, opts ...redisstore.Option) *redisstore.Store {
+	t.Helper()
+
+	store, err := redisstore.New(context.Background(), client, opts...)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		assert.NoError(t, store.Close())
+	})
+
+	return store
+}
+
+func TestStore(t *testing.T) {
+	t.Parallel()
+
+	mr := miniredis.RunT(t)
+	u := &url.URL{Scheme: "https", Host: "www.example.com", Path: "/"}
+
+	worker1, err := cookiejar.New(&cookiejar.Options{Store: newStore(t, newClient(t, mr))})
+	require.NoError(t, err)
+
+	worker2, err := cookiejar.New(&cookiejar.Options{Store: newStore(t, newClient(t, mr))})
+	require.NoError(t, err)
+
+	worker1.SetCookies(u, []*http.Cookie{
+		{Name: "id", Value: "42"},
+		{Name: "theme", Value: "dark", Domain: "example.com", MaxAge: 3600},
+	})
+	worker2.SetCookies(u, []*http.Cookie{{Name: "lang", Value: "en"}})
+
+	expected := []*http.Cookie{
+		{Name: "id", Value: "42"},
+		{Name: "theme", Value: "dark"},
+		{Name: "lang", Value: "en"},
+	}
+
+	assert.Equal(t, expected, worker1.Cookies(u))
+	assert.Equal(t, expected, worker2.Cookies(u))
+	assert.Equal(t, []string{"cookiejar:example.com"}, mr.Keys())
+
+	fields, err := mr.HKeys("cookiejar:example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"example.com;/;theme", "www.example.com;/;id", "www.example.com;/;lang"}, fields)
+
+	// A session cookie keeps the hash forever.
+	assert.Zero(t, mr.TTL("cookiejar:example.com"))
+
+	worker2.SetCookies(u, []*http.Cookie{{Name: "id", MaxAge: -1}, {Name: "lang", MaxAge: -1}})
+
+	// The hash expires with its last persistent cookie.
+	assert.InDelta(t, time.Hour, mr.TTL("cookiejar:example.com"), float64(time.Minute))
+
+	mr.FastForward(2 * time.Hour)
+
+	assert.Empty(t, worker1.Cookies(u))
+	assert.Empty(t, mr.Keys())
+}
+
+func TestStore_Update_Retry(t *testing.T) {
+	t.Parallel()
+
+	mr := miniredis.RunT(t)
+	store := newStore(t, newClient(t, mr))
+	other := newStore(t, newClient(t, mr))
+
+	calls := 0
+
+	err := store.Update("example.com", func(entries map[string]cookiejar.Entry) bool {
+		calls++
+
+		// The hash is modified by another process during the first attempt.
+		if calls == 1 {
+			require.NoError(t, other.Update("example.com", func(entries map[string]cookiejar.Entry) bool {
+				entries["example.com;/;theme"] = cookiejar.Entry{Name: "theme", Value: "dark", Domain: "example.com", Path: "/"}
+
+				return true
+			}))
+		}
+
+		entries["example.com;/;id"] = cookiejar.Entry{Name: "id", Value: "42", Domain: "example.com", Path: "/"}
+
+		return true
+	})
+	require.NoError(t, err)
+
+	fields, err := mr.HKeys("cookiejar:example.com")
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, []string{"example.com;/;id", "example.com;/;theme"}, fields)
+}
+
+func TestStore_Cache(t *testing.T) {
+	t.Parallel()
+
+	mr := miniredis.RunT(t)
+	u := &url.URL{Scheme: "https", Host: "example.com", Path: "/"}
+
+	worker1, err := cookiejar.New(&cookiejar.Options{
+		Store: newStore(t, newClient(t, mr), redisstore.WithCache(), redisstore.WithKeyPrefix("session:")),
+	})
+	require.NoError(t, err)
+
+	worker2, err := cookiejar.New(&cookiejar.Options{
+		Store: newStore(t, newClient(t, mr), redisstore.WithCache(), redisstore.WithKeyPrefix("session:")),
+	})
+	require.NoError(t, err)
+
+	// Cookies reads the hash from the cache of worker1.
+	assert.Empty(t, worker1.Cookies(u))
+
+	worker2.SetCookies(u, []*http.Cookie{{Name: "id", Value: "42"}})
+
+	// The cached empty hash of worker1 is dropped when worker2 publishes its update.
+	assert.Eventually(t, func() bool {
+		return len(worker1.Cookies(u)) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, []string{"session:example.com"}, mr.Keys())
+}
+
+func TestStore_Cache_LastAccess(t *testing.T) {
+	t.Parallel()
+
+	mr := miniredis.RunT(t)
+	client := newClient(t, mr)
+	u := &url.URL{Scheme: "https", Host: "example.com", Path: "/"}
+
+	store := newStore(t, client, redisstore.WithCache())
+
+	jar, err := cookiejar.New(&cookiejar.Options{Store: store})
+	require.NoError(t, err)
+
+	jar.SetCookies(u, []*http.Cookie{{Name: "id", Value: "42"}})
+
+	pubsub := client.Subscribe(context.Background(), "cookiejar:invalidate")
+
+	t.Cleanup(func() {
+		assert.NoError(t, pubsub.Close())
+	})
+
+	_, err = pubsub.Receive(context.Background())
+	require.NoError(t, err)
+
+	// The update that only writes the last access times is not published.
+	assert.Equal(t, []*http.Cookie{{Name: "id", Value: "42"}}, jar.Cookies(u))
+	require.NoError(t, jar.RemoveExpired())
+
+	lastAccess := jar.Entries()[0].LastAccess
+
+	data, err := client.HGet(context.Background(), "cookiejar:example.com", "example.com;/;id").Bytes()
+	require.NoError(t, err)
+
+	var stored cookiejar.Entry
+
+	require.NoError(t, json.Unmarshal(data, &stored))
+	assert.True(t, lastAccess.Equal(stored.LastAccess))
+
+	jar.SetCookies(&url.URL{Scheme: "https", Host: "example.org", Path: "/"}, []*http.Cookie{{Name: "id", Value: "42"}})
+
+	msg, err := pubsub.ReceiveMessage(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "example.org", msg.Payload)
+}
+
+// failingPublish is a redis.Hook that fails the PUBLISH commands.
+type failingPublish struct{}
+
+func (failingPublish) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (failingPublish) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if cmd.Name() == "publish" {
+			cmd.SetErr(errors.New("publish error"))
+
+			return cmd.Err()
+		}
+
+		return next(ctx, cmd)
+	}
+}
+
+func (failingPublish) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func TestStore_Cache_PublishError(t *testing.T) {
+	t.Parallel()
+
+	mr := miniredis.RunT(t)
+	client := newClient(t, mr)
+	logger := &ctxd.LoggerMock{}
+
+	client.AddHook(failingPublish{})
+
+	store := newStore(t, client, redisstore.WithCache(), redisstore.WithLogger(logger))
+
+	// The update is committed even if its invalidation cannot be published.
+	err := store.Update("example.com", func(entries map[string]cookiejar.Entry) bool {
+		entries["example.com;/;id"] = cookiejar.Entry{Name: "id", Value: "42", Domain: "example.com", Path: "/"}
+
+		return true
+	})
+	require.NoError(t, err)
+
+	fields, err := mr.HKeys("cookiejar:example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"example.com;/;id"}, fields)
+
+	require.Len(t, logger.LoggedEntries, 1)
+	assert.Equal(t, "could not publish cookies invalidation", logger.LoggedEntries[0].Message)
+}
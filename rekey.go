@@ -0,0 +1,126 @@
+package cookiejar
+
+import (
+	"cmp"
+	"slices"
+)
+
+// RekeyReport describes the changes made to the loaded cookies when their eTLD+1 keys were computed with a different
+// public suffix list.
+type RekeyReport struct {
+	// Moved contains the entries that are now stored under a different eTLD+1.
+	Moved []RekeyedEntry
+	// Dropped contains the entries that are illegal with the public suffix list, e.g. a domain cookie for a public
+	// suffix, and the older of two entries with the same id that end up under the same key.
+	Dropped []RekeyedEntry
+}
+
+// IsEmpty reports whether nothing changed.
+func (r RekeyReport) IsEmpty() bool {
+	return len(r.Moved) == 0 && len(r.Dropped) == 0
+}
+
+// RekeyedEntry is an entry that was moved or dropped while re-keying.
+type RekeyedEntry struct {
+	// ID is the domain;path;name triple of the entry.
+	ID string
+	// OldKey is the eTLD+1 key the entry was stored under.
+	OldKey string
+	// NewKey is the eTLD+1 key the entry is stored under now. It is empty for dropped entries.
+	NewKey string
+}
+
+// rekeyEntries recomputes the jar key of every entry with psl, moves the entries that are stored under a wrong key and
+// drops the ones that psl makes illegal. When two keys have an entry with the same id under the new key, the newest one
+// by creation time and then by sequence number is kept and the other one is dropped. The entries are returned as they
+// are if they are all keyed with psl already.
+func rekeyEntries(entries map[string]map[string]Entry, psl PublicSuffixList) (map[string]map[string]Entry, RekeyReport) {
+	var report RekeyReport
+
+	rekeyed := make(map[string]map[string]Entry, len(entries))
+	// oldKeys are the keys the kept entries were stored under.
+	oldKeys := make(map[string]map[string]string, len(entries))
+
+	for key, submap := range entries {
+		for _, e := range submap {
+			id := e.ID()
+
+			if !isLegalDomain(e.Domain, e.HostOnly, psl) {
+				report.Dropped = append(report.Dropped, RekeyedEntry{ID: id, OldKey: key})
+
+				continue
+			}
+
+			newKey := jarKey(e.Domain, psl)
+
+			if rekeyed[newKey] == nil {
+				rekeyed[newKey] = make(map[string]Entry)
+				oldKeys[newKey] = make(map[string]string)
+			}
+
+			if kept, ok := rekeyed[newKey][id]; ok {
+				if !isNewerEntry(e, kept) {
+					report.Dropped = append(report.Dropped, RekeyedEntry{ID: id, OldKey: key})
+
+					continue
+				}
+
+				report.Dropped = append(report.Dropped, RekeyedEntry{ID: id, OldKey: oldKeys[newKey][id]})
+			}
+
+			rekeyed[newKey][id] = e
+			oldKeys[newKey][id] = key
+		}
+	}
+
+	for newKey, ids := range oldKeys {
+		for id, key := range ids {
+			if key != newKey {
+				report.Moved = append(report.Moved, RekeyedEntry{ID: id, OldKey: key, NewKey: newKey})
+			}
+		}
+	}
+
+	if report.IsEmpty() {
+		return entries, report
+	}
+
+	// Make the report deterministic, map iteration order is not.
+	compareRekeyedEntries := func(a, b RekeyedEntry) int {
+		if r := cmp.Compare(a.OldKey, b.OldKey); r != 0 {
+			return r
+		}
+
+		return cmp.Compare(a.ID, b.ID)
+	}
+
+	slices.SortFunc(report.Moved, compareRekeyedEntries)
+	slices.SortFunc(report.Dropped, compareRekeyedEntries)
+
+	return rekeyed, report
+}
+
+// isNewerEntry reports whether a was created after b, or at the same time with a greater sequence number.
+func isNewerEntry(a, b Entry) bool {
+	if !a.Creation.Equal(b.Creation) {
+		return a.Creation.After(b.Creation)
+	}
+
+	return a.SeqNum > b.SeqNum
+}
+
+// isLegalDomain reports whether a stored cookie for domain is still acceptable with psl. Host-only cookies and cookies
+// for IP addresses always are, domain cookies must not be set for a public suffix.
+func isLegalDomain(domain string, hostOnly bool, psl PublicSuffixList) bool {
+	if domain == "" {
+		return false
+	}
+
+	if hostOnly || psl == nil || isIP(domain) {
+		return true
+	}
+
+	ps := psl.PublicSuffix(domain)
+
+	return ps == "" || hasDotSuffix(domain, ps)
+}
@@ -0,0 +1,214 @@
+package cookiejar
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"path"
+	"strings"
+)
+
+// SealedValue is the placeholder shown instead of the value of a sealed cookie.
+const SealedValue = "[sealed]"
+
+// sealPrefix is the prefix of a sealed value, followed by the base64url-encoded key id, nonce and ciphertext.
+const sealPrefix = "cjseal1."
+
+// SealRule selects the cookies whose value is sealed. Name and Domain are [path.Match] patterns matched against the
+// name and the domain of a cookie, e.g. "session*" or "*.example.com". An empty pattern matches every cookie.
+type SealRule struct {
+	Name   string
+	Domain string
+}
+
+// Sealer seals the values of the cookies that match its rules with AES-256-GCM. A sealed value is kept encrypted in
+// memory and at rest, it is decrypted only by Cookies for the outgoing cookies. Entries and HARCookies show SealedValue
+// instead, ExportSetCookies skips the sealed cookies.
+//
+// Whether a value is sealed is decided by the rules: the value of a matching cookie is sealed when it is set, the value
+// of another cookie is kept as is, even if it looks sealed. A loaded or imported value is only sealed if it does not
+// look sealed yet.
+//
+// The value is bound to the domain, path and name of the cookie, so it cannot be moved to another cookie.
+type Sealer struct {
+	keys  KeyProvider
+	rules []SealRule
+}
+
+// NewSealer returns a sealer for the cookies that match any of rules, with the keys of keys. As with
+// NewEncryptedSerDer, the first key seals and all of them unseal, so the keys can be rotated with RotateKeys.
+func NewSealer(keys KeyProvider, rules ...SealRule) *Sealer {
+	return &Sealer{
+		keys:  keys,
+		rules: rules,
+	}
+}
+
+// Matches reports whether the value of the cookie with name and domain is sealed.
+func (s *Sealer) Matches(name, domain string) bool {
+	if s == nil {
+		return false
+	}
+
+	for _, r := range s.rules {
+		if matchSealPattern(r.Name, name) && matchSealPattern(r.Domain, domain) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// seal encrypts the value of e if it matches the rules. The value is set by the server, it is sealed even if it has the
+// prefix of a sealed value.
+func (s *Sealer) seal(e *entry) error {
+	if !s.Matches(e.Name, e.Domain) {
+		return nil
+	}
+
+	keys, err := providedKeys(s.keys)
+	if err != nil {
+		return err
+	}
+
+	aead, err := newAEAD(keys[0])
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	sealed := make([]byte, 0, encryptionKeyIDSize+len(nonce)+len(e.Value)+aead.Overhead())
+	sealed = append(sealed, keyID(keys[0])...)
+	sealed = append(sealed, nonce...)
+	sealed = aead.Seal(sealed, nonce, []byte(e.Value), []byte(e.id()))
+
+	e.Value = sealPrefix + base64.RawURLEncoding.EncodeToString(sealed)
+
+	return nil
+}
+
+// unseal returns the decrypted value of e. A value that is not sealed is returned as is, as is the value of a cookie
+// that does not match the rules and cannot be decrypted: it is a value of the server with the prefix of a sealed value.
+func (s *Sealer) unseal(e entry) (string, error) {
+	if !isSealed(e.Value) {
+		return e.Value, nil
+	}
+
+	value, err := s.open(e)
+	if err != nil && !s.Matches(e.Name, e.Domain) {
+		return e.Value, nil
+	}
+
+	return value, err
+}
+
+// open decrypts the sealed value of e.
+func (s *Sealer) open(e entry) (string, error) {
+	if s == nil {
+		return "", errNoKeys
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(e.Value, sealPrefix))
+	if err != nil || len(data) < encryptionKeyIDSize {
+		return "", ErrCorruptData
+	}
+
+	keys, err := providedKeys(s.keys)
+	if err != nil {
+		return "", err
+	}
+
+	for _, key := range keys {
+		if !bytes.Equal(keyID(key), data[:encryptionKeyIDSize]) {
+			continue
+		}
+
+		aead, err := newAEAD(key)
+		if err != nil {
+			return "", err
+		}
+
+		if len(data) < encryptionKeyIDSize+aead.NonceSize() {
+			return "", ErrCorruptData
+		}
+
+		nonce := data[encryptionKeyIDSize : encryptionKeyIDSize+aead.NonceSize()]
+
+		plaintext, err := aead.Open(nil, nonce, data[encryptionKeyIDSize+aead.NonceSize():], []byte(e.id()))
+		if err != nil {
+			return "", ErrCorruptData
+		}
+
+		return string(plaintext), nil
+	}
+
+	return "", ErrWrongKey
+}
+
+// redact replaces the value of e with SealedValue if it matches the rules.
+func (s *Sealer) redact(e Entry) Entry {
+	if s.hides(e) {
+		e.Value = SealedValue
+	}
+
+	return e
+}
+
+// hides reports whether the value of e matches the rules, and is only known to Cookies.
+func (s *Sealer) hides(e Entry) bool {
+	return s.Matches(e.Name, e.Domain)
+}
+
+func isSealed(value string) bool {
+	return strings.HasPrefix(value, sealPrefix)
+}
+
+func matchSealPattern(pattern, s string) bool {
+	if pattern == "" {
+		return true
+	}
+
+	ok, err := path.Match(pattern, s)
+
+	return err == nil && ok
+}
+
+// sealEntries seals the values of the loaded entries that match the rules of the jar. The errors are passed to the
+// StoreErrorHandler and the entry is kept as is.
+func (j *Jar) sealEntries(entries map[string]map[string]Entry) {
+	if j.sealer == nil {
+		return
+	}
+
+	for _, submap := range entries {
+		for id, e := range submap {
+			if sealed, ok := j.sealExported(e); ok {
+				submap[id] = sealed
+			}
+		}
+	}
+}
+
+// sealExported seals the value of e if it matches the rules of the jar and is not sealed yet. It reports whether the
+// value changed.
+func (j *Jar) sealExported(e Entry) (Entry, bool) {
+	if j.sealer == nil || isSealed(e.Value) || !j.sealer.Matches(e.Name, e.Domain) {
+		return e, false
+	}
+
+	imported := importEntry(e)
+
+	if err := j.sealer.seal(&imported); err != nil {
+		j.storeError(err)
+
+		return e, false
+	}
+
+	e.Value = imported.Value
+
+	return e, true
+}
@@ -0,0 +1,173 @@
+package cookiejar_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.nhat.io/cookiejar"
+)
+
+func TestSealer_Matches(t *testing.T) {
+	t.Parallel()
+
+	s := cookiejar.NewSealer(cookiejar.RawKey(newKey),
+		cookiejar.SealRule{Name: "session*"},
+		cookiejar.SealRule{Name: "csrf", Domain: "*.example.com"},
+	)
+
+	testCases := []struct {
+		scenario string
+		name     string
+		domain   string
+		expected bool
+	}{
+		{scenario: "name pattern", name: "session_id", domain: "example.org", expected: true},
+		{scenario: "name and domain", name: "csrf", domain: "www.example.com", expected: true},
+		{scenario: "other domain", name: "csrf", domain: "example.org"},
+		{scenario: "other name", name: "lang", domain: "www.example.com"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, s.Matches(tc.name, tc.domain))
+		})
+	}
+
+	assert.False(t, (*cookiejar.Sealer)(nil).Matches("session", "example.com"))
+}
+
+func TestJar_Sealer(t *testing.T) {
+	t.Parallel()
+
+	var errs []error
+
+	jar, err := cookiejar.New(&cookiejar.Options{
+		Sealer:            cookiejar.NewSealer(cookiejar.RawKey(newKey), cookiejar.SealRule{Name: "session"}),
+		StoreErrorHandler: func(err error) { errs = append(errs, err) },
+	})
+	require.NoError(t, err)
+
+	u := &url.URL{Scheme: "https", Host: "example.com", Path: "/"}
+
+	jar.SetCookies(u, []*http.Cookie{{Name: "session", Value: "secret"}, {Name: "lang", Value: "en"}})
+
+	assert.Equal(t, []*http.Cookie{{Name: "session", Value: "secret"}, {Name: "lang", Value: "en"}}, jar.Cookies(u))
+	assert.Equal(t, "session=secret; lang=en", jar.CookieHeader(u))
+
+	entries := jar.Entries()
+
+	require.Len(t, entries, 2)
+	assert.Equal(t, cookiejar.SealedValue, entries[0].Value)
+	assert.Equal(t, "en", entries[1].Value)
+
+	assert.Equal(t, []*http.Cookie{{Name: "lang", Value: "en", Path: "/"}}, jar.ExportSetCookies("example.com"))
+
+	har := jar.HARCookies(u)
+
+	require.Len(t, har, 2)
+	assert.Equal(t, cookiejar.SealedValue, har[0].Value)
+	assert.Equal(t, "en", har[1].Value)
+
+	assert.Empty(t, errs)
+}
+
+func TestPersistentJar_Sealer(t *testing.T) {
+	t.Parallel()
+
+	const filePath = "/tmp/cookies.json"
+
+	fs := afero.NewMemMapFs()
+	u := &url.URL{Scheme: "https", Host: "example.com", Path: "/"}
+
+	newJar := func(key []byte) *cookiejar.PersistentJar {
+		return cookiejar.NewPersistentJar(
+			cookiejar.WithFs(fs),
+			cookiejar.WithFilePath(filePath),
+			cookiejar.WithSealer(cookiejar.NewSealer(cookiejar.RawKey(key), cookiejar.SealRule{Name: "session"})),
+		)
+	}
+
+	readFile := func() map[string]map[string]cookiejar.Entry {
+		data, err := afero.ReadFile(fs, filePath)
+		require.NoError(t, err)
+
+		assert.NotContains(t, string(data), "secret")
+
+		var entries map[string]map[string]cookiejar.Entry
+
+		require.NoError(t, json.Unmarshal(data, &entries))
+
+		return entries
+	}
+
+	// The values of a plain text file are sealed when it is loaded.
+	plain := cookiejar.NewPersistentJar(cookiejar.WithFs(fs), cookiejar.WithFilePath(filePath))
+	plain.SetCookies(u, []*http.Cookie{{Name: "session", Value: "secret"}, {Name: "lang", Value: "en"}})
+	require.NoError(t, plain.Sync())
+
+	j := newJar(newKey)
+
+	assert.Equal(t, []*http.Cookie{{Name: "session", Value: "secret"}, {Name: "lang", Value: "en"}}, j.Cookies(u))
+	require.NoError(t, j.Sync())
+
+	// A sealed cookie is not exported, a Set-Cookie with its placeholder would overwrite it.
+	assert.Equal(t, []*http.Cookie{{Name: "lang", Value: "en", Path: "/"}}, j.ExportSetCookies("example.com"))
+
+	sealed := readFile()["example.com"]["example.com;/;session"]
+
+	assert.NotEqual(t, "secret", sealed.Value)
+
+	// The value is decrypted with the key.
+	assert.Equal(t, []*http.Cookie{{Name: "session", Value: "secret"}, {Name: "lang", Value: "en"}}, newJar(newKey).Cookies(u))
+
+	// A cookie that cannot be unsealed is not sent.
+	assert.Equal(t, []*http.Cookie{{Name: "lang", Value: "en"}}, newJar(oldKey).Cookies(u))
+
+	// A sealed value cannot be moved to another cookie.
+	moved := newJar(newKey)
+
+	sealed.Path = "/account"
+	moved.ImportEntries(sealed)
+
+	assert.Equal(t, []*http.Cookie{{Name: "session", Value: "secret"}, {Name: "lang", Value: "en"}},
+		moved.Cookies(&url.URL{Scheme: "https", Host: "example.com", Path: "/account"}))
+}
+
+func TestJar_Sealer_PrefixedValue(t *testing.T) {
+	t.Parallel()
+
+	const value = "cjseal1.not-sealed"
+
+	var errs []error
+
+	jar, err := cookiejar.New(&cookiejar.Options{
+		Sealer:            cookiejar.NewSealer(cookiejar.RawKey(newKey), cookiejar.SealRule{Name: "session"}),
+		StoreErrorHandler: func(err error) { errs = append(errs, err) },
+	})
+	require.NoError(t, err)
+
+	u := &url.URL{Scheme: "https", Host: "example.com", Path: "/"}
+
+	// The values of the server look sealed, the one of the matching cookie is sealed anyway.
+	jar.SetCookies(u, []*http.Cookie{{Name: "session", Value: value}, {Name: "lang", Value: value}})
+
+	assert.Equal(t, []*http.Cookie{{Name: "session", Value: value}, {Name: "lang", Value: value}}, jar.Cookies(u))
+
+	entries := jar.Entries()
+
+	require.Len(t, entries, 2)
+	assert.Equal(t, cookiejar.SealedValue, entries[0].Value)
+	assert.Equal(t, value, entries[1].Value)
+
+	assert.Equal(t, []*http.Cookie{{Name: "lang", Value: value, Path: "/"}}, jar.ExportSetCookies("example.com"))
+
+	assert.Empty(t, errs)
+}
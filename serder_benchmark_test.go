@@ -0,0 +1,83 @@
+package cookiejar
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func benchmarkEntries() map[string]map[string]Entry {
+	entries := make(map[string]map[string]Entry)
+	now := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+
+	for i := range 100 {
+		key := fmt.Sprintf("example%d.com", i)
+		entries[key] = make(map[string]Entry)
+
+		for j := range 10 {
+			e := Entry{
+				Name:         fmt.Sprintf("cookie%d", j),
+				Value:        "ZXhhbXBsZSB2YWx1ZSBvZiBhIGNvb2tpZQ",
+				Quoted:       j%2 == 0,
+				Domain:       key,
+				Path:         "/",
+				SameSite:     "SameSite=Lax",
+				Secure:       true,
+				HttpOnly:     true,
+				Persistent:   true,
+				HostOnly:     j%2 == 1,
+				Partitioned:  j%3 == 0,
+				PartitionKey: "https://example.org",
+				Expires:      now.Add(24 * time.Hour),
+				Creation:     now,
+				LastAccess:   now.Add(time.Minute),
+				SeqNum:       uint64(i*10 + j), //nolint: gosec
+			}
+
+			entries[key][e.ID()] = e
+		}
+	}
+
+	return entries
+}
+
+func BenchmarkSerDer_RoundTrip(b *testing.B) {
+	entries := benchmarkEntries()
+
+	benchmarks := []struct {
+		name   string
+		serder EntrySerDer
+	}{
+		{name: "json", serder: jsonSerDer{}},
+		{name: "gob", serder: NewGobSerDer()},
+		{name: "cbor", serder: NewCBORSerDer()},
+		{name: "protobuf", serder: NewProtobufSerDer()},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			var buf bytes.Buffer
+
+			if err := bm.serder.Serialize(&buf, entries); err != nil {
+				b.Fatal(err)
+			}
+
+			b.ReportMetric(float64(buf.Len()), "bytes/file")
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for range b.N {
+				buf.Reset()
+
+				if err := bm.serder.Serialize(&buf, entries); err != nil {
+					b.Fatal(err)
+				}
+
+				if _, err := bm.serder.Deserialize(&buf); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,301 @@
+package cookiejar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/bool64/ctxd"
+	"github.com/spf13/afero"
+)
+
+// siteFileExt is the extension of the file of a site.
+const siteFileExt = ".cookies"
+
+var _ Store = (*siteFiles)(nil)
+
+// siteFiles is the store of a PersistentJar in the per-site layout. The file path of the jar is a directory with one
+// file per eTLD+1, written with the serializer of the jar. The file of a site is read the first time the site is
+// accessed and only the sites that changed are written by Sync.
+//
+// The store is used with the lock of the jar held, it does not have its own.
+type siteFiles struct {
+	fs       afero.Fs
+	dir      string
+	filePerm os.FileMode
+	serder   EntrySerDer
+	psList   PublicSuffixList
+	logger   ctxd.Logger
+	onRekey  func(report RekeyReport)
+	seal     func(e Entry) (Entry, bool)
+	onDrop   func(loaded map[string]map[string]Entry, dropped []RekeyedEntry)
+
+	entries map[string]map[string]Entry
+	dirty   map[string]bool
+}
+
+func newSiteFiles() *siteFiles {
+	return &siteFiles{
+		entries: make(map[string]map[string]Entry),
+		dirty:   make(map[string]bool),
+	}
+}
+
+func (s *siteFiles) Entries(key string) (map[string]Entry, error) {
+	entries, err := s.load(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return maps.Clone(entries), nil
+}
+
+// All reads the files of every site.
+func (s *siteFiles) All() (map[string]map[string]Entry, error) {
+	files, err := afero.ReadDir(s.fs, filepath.Clean(s.dir))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("could not list cookies directory: %w", err)
+	}
+
+	for _, f := range files {
+		key, ok := siteKey(f.Name())
+		if !ok || f.IsDir() {
+			continue
+		}
+
+		if _, err := s.load(key); err != nil {
+			return nil, err
+		}
+	}
+
+	all := make(map[string]map[string]Entry, len(s.entries))
+
+	for key, entries := range s.entries {
+		if len(entries) > 0 {
+			all[key] = maps.Clone(entries)
+		}
+	}
+
+	return all, nil
+}
+
+func (s *siteFiles) Update(key string, fn func(entries map[string]Entry) bool) error {
+	before, err := s.load(key)
+	if err != nil {
+		return err
+	}
+
+	after := maps.Clone(before)
+
+	if !fn(after) {
+		return nil
+	}
+
+	s.entries[key] = after
+	s.dirty[key] = true
+
+	return nil
+}
+
+// load returns the entries of key, reading the file of the site if needed. The entries that belong to another site
+// with the public suffix list are moved to that site. The caller must not modify the result.
+func (s *siteFiles) load(key string) (map[string]Entry, error) {
+	if entries, ok := s.entries[key]; ok {
+		return entries, nil
+	}
+
+	path := s.path(key)
+	ctx := ctxd.AddFields(context.Background(), "cookies.file", path)
+
+	loaded, err := s.read(path)
+	if err != nil {
+		return nil, ctxd.WrapError(ctx, err, "could not load cookies")
+	}
+
+	// Mark the site as loaded before moving the entries, so that a site that moves entries back does not load again.
+	s.entries[key] = make(map[string]Entry)
+
+	read := loaded
+	loaded, report := rekeyEntries(read, s.psList)
+
+	s.onDrop(read, report.Dropped)
+
+	for k, entries := range loaded {
+		target := s.entries[key]
+
+		if k != key {
+			if target, err = s.load(k); err != nil {
+				return nil, err
+			}
+
+			s.dirty[k] = true
+			s.dirty[key] = true
+		}
+
+		for id, e := range entries {
+			if sealed, ok := s.seal(e); ok {
+				e = sealed
+				s.dirty[k] = true
+			}
+
+			target[id] = e
+		}
+	}
+
+	if !report.IsEmpty() {
+		s.dirty[key] = true
+
+		s.logger.Warn(ctx, "re-keyed cookies with the public suffix list",
+			"cookies.moved", len(report.Moved),
+			"cookies.dropped", len(report.Dropped),
+		)
+
+		if s.onRekey != nil {
+			s.onRekey(report)
+		}
+	}
+
+	return s.entries[key], nil
+}
+
+// read deserializes the file at path. A missing file has no entries.
+func (s *siteFiles) read(path string) (map[string]map[string]Entry, error) {
+	f, err := s.fs.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	defer func() {
+		_ = f.Close() //nolint: errcheck
+	}()
+
+	return s.serder.Deserialize(f)
+}
+
+// sync writes the files of the sites that changed and removes the files of the sites that have no entries left.
+func (s *siteFiles) sync() error {
+	if len(s.dirty) == 0 {
+		return nil
+	}
+
+	if err := s.fs.MkdirAll(filepath.Clean(s.dir), 0o700); err != nil {
+		return fmt.Errorf("could not create cookies directory: %w", err)
+	}
+
+	var errs []error
+
+	for key := range s.dirty {
+		if err := s.write(key); err != nil {
+			errs = append(errs, fmt.Errorf("could not persist cookies of %q: %w", key, err))
+
+			continue
+		}
+
+		delete(s.dirty, key)
+	}
+
+	return errors.Join(errs...)
+}
+
+func (s *siteFiles) write(key string) error {
+	path := s.path(key)
+	entries := s.entries[key]
+
+	if len(entries) == 0 {
+		if err := s.fs.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+
+		return nil
+	}
+
+	tmpPath := path + ".tmp"
+
+	f, err := s.fs.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, s.filePerm)
+	if err != nil {
+		return err
+	}
+
+	err = s.serder.Serialize(f, map[string]map[string]Entry{key: entries})
+	if err == nil {
+		err = f.Sync()
+	}
+
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		_ = s.fs.Remove(tmpPath) //nolint: errcheck
+
+		return err
+	}
+
+	return s.fs.Rename(tmpPath, path)
+}
+
+func (s *siteFiles) path(key string) string {
+	return filepath.Join(filepath.Clean(s.dir), siteFileName(key))
+}
+
+// siteFileName encodes key as a file name. The lowercase letters, digits, hyphens and non-leading dots are kept, the
+// other bytes are percent-encoded, so that the name is safe on every file system and cannot be "." or "..".
+func siteFileName(key string) string {
+	var sb strings.Builder
+
+	for i := range len(key) {
+		c := key[i]
+
+		if c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '.' && i > 0 {
+			sb.WriteByte(c)
+
+			continue
+		}
+
+		fmt.Fprintf(&sb, "%%%02X", c)
+	}
+
+	return sb.String() + siteFileExt
+}
+
+// siteKey decodes the key of a file name made by siteFileName.
+func siteKey(name string) (string, bool) {
+	encoded, ok := strings.CutSuffix(name, siteFileExt)
+	if !ok {
+		return "", false
+	}
+
+	var sb strings.Builder
+
+	for i := 0; i < len(encoded); i++ {
+		if encoded[i] != '%' {
+			sb.WriteByte(encoded[i])
+
+			continue
+		}
+
+		if i+2 >= len(encoded) {
+			return "", false
+		}
+
+		c, err := strconv.ParseUint(encoded[i+1:i+3], 16, 8)
+		if err != nil {
+			return "", false
+		}
+
+		sb.WriteByte(byte(c))
+
+		i += 2
+	}
+
+	return sb.String(), siteFileName(sb.String()) == name
+}
@@ -0,0 +1,73 @@
+package cookiejar_test
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.nhat.io/cookiejar"
+)
+
+func TestPersistentJar_SiteFiles(t *testing.T) {
+	t.Parallel()
+
+	const dir = "/tmp/cookies"
+
+	fs := afero.NewMemMapFs()
+	exampleCom := &url.URL{Scheme: "https", Host: "www.example.com", Path: "/"}
+	exampleOrg := &url.URL{Scheme: "https", Host: "example.org", Path: "/"}
+
+	newJar := func() *cookiejar.PersistentJar {
+		return cookiejar.NewPersistentJar(cookiejar.WithFs(fs), cookiejar.WithFilePath(dir), cookiejar.WithSiteFiles())
+	}
+
+	j := newJar()
+
+	j.SetCookies(exampleCom, []*http.Cookie{{Name: "id", Value: "42"}})
+	j.SetCookies(exampleOrg, []*http.Cookie{{Name: "lang", Value: "en"}})
+
+	require.NoError(t, j.Sync())
+
+	names := func() []string {
+		files, err := afero.ReadDir(fs, dir)
+		require.NoError(t, err)
+
+		result := make([]string, 0, len(files))
+
+		for _, f := range files {
+			result = append(result, f.Name())
+		}
+
+		return result
+	}
+
+	assert.Equal(t, []string{"example.com.cookies", "example.org.cookies"}, names())
+
+	// Only the sites that changed are written.
+	require.NoError(t, fs.Remove(dir+"/example.org.cookies"))
+
+	j.SetCookies(exampleCom, []*http.Cookie{{Name: "theme", Value: "dark"}})
+	require.NoError(t, j.Sync())
+
+	assert.Equal(t, []string{"example.com.cookies"}, names())
+
+	// The site of a broken file is not read until it is accessed.
+	require.NoError(t, afero.WriteFile(fs, dir+"/example.org.cookies", []byte("{"), 0o600))
+
+	reopened := newJar()
+
+	expected := []*http.Cookie{{Name: "id", Value: "42"}, {Name: "theme", Value: "dark"}}
+
+	assert.Equal(t, expected, reopened.Cookies(exampleCom))
+	assert.Empty(t, reopened.Cookies(exampleOrg))
+
+	// A site without cookies has no file.
+	reopened.SetCookies(exampleCom, []*http.Cookie{{Name: "id", MaxAge: -1}, {Name: "theme", MaxAge: -1}})
+	require.NoError(t, reopened.Sync())
+
+	assert.Equal(t, []string{"example.org.cookies"}, names())
+}
@@ -0,0 +1,3 @@
+// Package sqlitestore stores the entries of a cookiejar.Jar in a SQLite database, with the pure-Go modernc.org/sqlite
+// driver.
+package sqlitestore
@@ -0,0 +1,210 @@
+package sqlitestore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"time"
+
+	"go.nhat.io/cookiejar"
+	"go.nhat.io/cookiejar/internal/sqlrow"
+
+	_ "modernc.org/sqlite" // Pure-Go SQLite driver.
+)
+
+const schema = `CREATE TABLE IF NOT EXISTS cookies (
+	etld1         TEXT    NOT NULL,
+	domain        TEXT    NOT NULL,
+	path          TEXT    NOT NULL,
+	name          TEXT    NOT NULL,
+	value         TEXT    NOT NULL,
+	quoted        INTEGER NOT NULL,
+	same_site     TEXT    NOT NULL,
+	secure        INTEGER NOT NULL,
+	http_only     INTEGER NOT NULL,
+	persistent    INTEGER NOT NULL,
+	host_only     INTEGER NOT NULL,
+	partitioned   INTEGER NOT NULL,
+	partition_key TEXT    NOT NULL,
+	expires       TEXT    NOT NULL,
+	creation      TEXT    NOT NULL,
+	last_access   TEXT    NOT NULL,
+	seq_num       INTEGER NOT NULL,
+	PRIMARY KEY (etld1, domain, path, name)
+) WITHOUT ROWID;
+
+CREATE INDEX IF NOT EXISTS cookies_expires ON cookies (expires) WHERE persistent;`
+
+const columns = `domain, path, name, value, quoted, same_site, secure, http_only, persistent, host_only, partitioned,
+	partition_key, expires, creation, last_access, seq_num`
+
+const upsert = `INSERT INTO cookies (etld1, ` + columns + `)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (etld1, domain, path, name) DO UPDATE SET
+	value = excluded.value,
+	quoted = excluded.quoted,
+	same_site = excluded.same_site,
+	secure = excluded.secure,
+	http_only = excluded.http_only,
+	persistent = excluded.persistent,
+	host_only = excluded.host_only,
+	partitioned = excluded.partitioned,
+	partition_key = excluded.partition_key,
+	expires = excluded.expires,
+	creation = excluded.creation,
+	last_access = excluded.last_access,
+	seq_num = excluded.seq_num`
+
+var _ cookiejar.Store = (*Store)(nil)
+
+var _ cookiejar.ExpiredRemover = (*Store)(nil)
+
+// Store is a cookiejar.Store in a SQLite database.
+//
+// Every entry is a row of the cookies table, keyed by the eTLD+1, domain, path and name. An update only writes the
+// rows that changed, in one transaction. The database is in WAL mode, so that the readers do not block the writer and
+// the database can be shared by several processes.
+type Store struct {
+	db *sql.DB
+}
+
+// Open opens or creates the SQLite database at path and creates the cookies table if it does not exist.
+func Open(path string) (*Store, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open cookies database: %w", err)
+	}
+
+	dsn := url.URL{
+		Scheme: "file",
+		Path:   filepath.ToSlash(path),
+		RawQuery: url.Values{
+			"_pragma": {"journal_mode(WAL)", "busy_timeout(5000)", "synchronous(NORMAL)"},
+			"_txlock": {"immediate"},
+		}.Encode(),
+	}
+
+	db, err := sql.Open("sqlite", dsn.String())
+	if err != nil {
+		return nil, fmt.Errorf("could not open cookies database: %w", err)
+	}
+
+	if _, err := db.Exec(schema); err != nil {
+		_ = db.Close() //nolint: errcheck
+
+		return nil, fmt.Errorf("could not create cookies table: %w", err)
+	}
+
+	return &Store{db: db}, nil
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Entries returns the entries of key.
+func (s *Store) Entries(key string) (map[string]cookiejar.Entry, error) {
+	entries, err := queryEntries(context.Background(), s.db, `WHERE etld1 = ?`, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return entries[key], nil
+}
+
+// All returns the entries of every key.
+func (s *Store) All() (map[string]map[string]cookiejar.Entry, error) {
+	return queryEntries(context.Background(), s.db, "")
+}
+
+// Update reads the entries of key, calls fn and writes the upserted and deleted entries in one transaction.
+func (s *Store) Update(key string, fn func(entries map[string]cookiejar.Entry) bool) (err error) {
+	ctx := context.Background()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("could not begin cookies transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback() //nolint: errcheck
+		}
+	}()
+
+	all, err := queryEntries(ctx, tx, `WHERE etld1 = ?`, key)
+	if err != nil {
+		return err
+	}
+
+	before := all[key]
+	after := make(map[string]cookiejar.Entry, len(before))
+
+	for id, e := range before {
+		after[id] = e
+	}
+
+	if !fn(after) {
+		return tx.Rollback()
+	}
+
+	upserts, deletes := cookiejar.DiffEntries(before, after)
+
+	for _, e := range deletes {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM cookies WHERE etld1 = ? AND domain = ? AND path = ? AND name = ?`,
+			key, e.Domain, e.Path, e.Name,
+		); err != nil {
+			return fmt.Errorf("could not delete cookie: %w", err)
+		}
+	}
+
+	for _, e := range upserts {
+		if _, err := tx.ExecContext(ctx, upsert, sqlrow.Values(e, key)...); err != nil {
+			return fmt.Errorf("could not upsert cookie: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("could not commit cookies transaction: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveExpired deletes the persistent entries that expire at or before now and returns them.
+func (s *Store) RemoveExpired(now time.Time) (map[string]map[string]cookiejar.Entry, error) {
+	rows, err := s.db.Query(`DELETE FROM cookies WHERE persistent AND expires <= ? RETURNING etld1, `+columns,
+		sqlrow.FormatTime(now),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not remove expired cookies: %w", err)
+	}
+
+	defer rows.Close() //nolint: errcheck
+
+	removed, err := sqlrow.Scan(rows)
+	if err != nil {
+		return nil, fmt.Errorf("could not remove expired cookies: %w", err)
+	}
+
+	return removed, nil
+}
+
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// queryEntries reads the entries of the rows that match the where clause, grouped by their eTLD+1.
+func queryEntries(ctx context.Context, q querier, where string, args ...any) (map[string]map[string]cookiejar.Entry, error) {
+	rows, err := q.QueryContext(ctx, `SELECT etld1, `+columns+` FROM cookies `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("could not query cookies: %w", err)
+	}
+
+	defer rows.Close() //nolint: errcheck
+
+	return sqlrow.Scan(rows)
+}
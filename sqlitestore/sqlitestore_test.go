@@ -0,0 +1,118 @@
+package sqlitestore_test
+
+import (
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.nhat.io/cookiejar"
+	"go.nhat.io/cookiejar/sqlitestore"
+)
+
+func openStore(t *testing.T, path string) *sqlitestore.Store {
+	t.Helper()
+
+	store, err := sqlitestore.Open(path)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		assert.NoError(t, store.Close())
+	})
+
+	return store
+}
+
+func TestStore(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "cookies.db")
+	u := &url.URL{Scheme: "https", Host: "www.example.com", Path: "/app/"}
+	expires := time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	jar, err := cookiejar.New(&cookiejar.Options{Store: openStore(t, path)})
+	require.NoError(t, err)
+
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "id", Value: "42", Secure: true, HttpOnly: true, SameSite: http.SameSiteStrictMode},
+		{Name: "theme", Value: "dark", Domain: "example.com", Path: "/", Expires: expires, Partitioned: true},
+		{Name: "quoted", Value: "a b", Quoted: true},
+	})
+
+	// A jar on another connection to the same database sees the same cookies.
+	reopened, err := cookiejar.New(&cookiejar.Options{Store: openStore(t, path)})
+	require.NoError(t, err)
+
+	expected := []*http.Cookie{
+		{Name: "id", Value: "42"},
+		{Name: "quoted", Value: "a b", Quoted: true},
+		{Name: "theme", Value: "dark"},
+	}
+
+	assert.Equal(t, expected, reopened.Cookies(u))
+
+	// The last access times of the sent cookies are written by RemoveExpired, or with the next change.
+	require.NoError(t, reopened.RemoveExpired())
+	assert.Equal(t, jar.Entries(), reopened.Entries())
+
+	entries := reopened.Entries()
+
+	require.Len(t, entries, 3)
+	assert.Equal(t, cookiejar.Entry{
+		Name:        "theme",
+		Value:       "dark",
+		Domain:      "example.com",
+		Path:        "/",
+		Persistent:  true,
+		Partitioned: true,
+		Expires:     expires,
+		Creation:    entries[1].Creation,
+		LastAccess:  entries[1].LastAccess,
+		SeqNum:      1,
+	}, entries[1])
+
+	reopened.SetCookies(u, []*http.Cookie{{Name: "id", MaxAge: -1}})
+
+	assert.Equal(t, []*http.Cookie{{Name: "quoted", Value: "a b", Quoted: true}, {Name: "theme", Value: "dark"}},
+		jar.Cookies(u))
+}
+
+func TestStore_RemoveExpired(t *testing.T) {
+	t.Parallel()
+
+	store := openStore(t, filepath.Join(t.TempDir(), "cookies.db"))
+
+	jar, err := cookiejar.New(&cookiejar.Options{Store: store})
+	require.NoError(t, err)
+
+	jar.ImportEntries(
+		cookiejar.Entry{Name: "session", Value: "1", Domain: "example.com", Path: "/", HostOnly: true},
+		cookiejar.Entry{
+			Name: "persistent", Value: "1", Domain: "example.com", Path: "/", HostOnly: true,
+			Persistent: true, Expires: time.Now().Add(time.Hour),
+		},
+		cookiejar.Entry{
+			Name: "expiring", Value: "1", Domain: "example.org", Path: "/", HostOnly: true,
+			Persistent: true, Expires: time.Now().Add(50 * time.Millisecond),
+		},
+	)
+
+	time.Sleep(100 * time.Millisecond)
+
+	removed, err := store.RemoveExpired(time.Now())
+	require.NoError(t, err)
+
+	// The removed entries are returned to be audited.
+	require.Len(t, removed, 1)
+	assert.Equal(t, "1", removed["example.org"]["example.org;/;expiring"].Value)
+
+	all, err := store.All()
+	require.NoError(t, err)
+
+	assert.Len(t, all, 1)
+	assert.Len(t, all["example.com"], 2)
+}
@@ -0,0 +1,147 @@
+package sqlstore
+
+import (
+	"strconv"
+	"strings"
+)
+
+var (
+	_ Dialect = sqliteDialect{}
+	_ Dialect = postgresDialect{}
+	_ Dialect = mysqlDialect{}
+)
+
+// Dialect adapts the queries of the store to a database.
+type Dialect interface {
+	// Name is the name of the dialect, it selects the migrations of the dialect.
+	Name() string
+
+	// Rebind replaces the ? placeholders of query with the placeholders of the dialect.
+	Rebind(query string) string
+
+	// Upsert returns the statement that inserts a row of columns in table, or updates the columns of the row that has
+	// the same keys. The keys are the first columns.
+	Upsert(table string, keys, columns []string) string
+
+	// LockRows returns the clause that locks the rows read by a SELECT until the end of the transaction.
+	LockRows() string
+
+	// LockKey returns the statement that locks a key, its only argument, until the end of the transaction, so that the
+	// updates of a key are serialized even when the key has no rows to lock yet. It is empty if LockRows is enough.
+	LockKey() string
+
+	// LockMigrations returns the statements that take and release the lock of the migrations on a connection, so that
+	// concurrent calls to Migrate do not apply a migration twice. They are empty if the dialect does not need them.
+	LockMigrations() (lock, unlock string)
+}
+
+// Dialects of the supported databases.
+var (
+	SQLite   Dialect = sqliteDialect{}
+	Postgres Dialect = postgresDialect{}
+	MySQL    Dialect = mysqlDialect{}
+)
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string { return "sqlite" }
+
+func (sqliteDialect) Rebind(query string) string { return query }
+
+func (sqliteDialect) Upsert(table string, keys, columns []string) string {
+	return onConflictUpsert(table, keys, columns)
+}
+
+// LockRows is empty, as SQLite locks the whole database. The transactions should be immediate, e.g. with the
+// "_txlock=immediate" parameter of modernc.org/sqlite, so that they do not fail when upgrading to a write lock.
+func (sqliteDialect) LockRows() string { return "" }
+
+func (sqliteDialect) LockKey() string { return "" }
+
+// LockMigrations is empty, as the immediate transactions of the migrations lock the whole database.
+func (sqliteDialect) LockMigrations() (string, string) { return "", "" }
+
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string { return "postgres" }
+
+func (postgresDialect) Rebind(query string) string {
+	var (
+		sb strings.Builder
+		n  int
+	)
+
+	for _, r := range query {
+		if r != '?' {
+			sb.WriteRune(r)
+
+			continue
+		}
+
+		n++
+
+		sb.WriteByte('$')
+		sb.WriteString(strconv.Itoa(n))
+	}
+
+	return sb.String()
+}
+
+func (postgresDialect) Upsert(table string, keys, columns []string) string {
+	return onConflictUpsert(table, keys, columns)
+}
+
+func (postgresDialect) LockRows() string { return "FOR UPDATE" }
+
+// LockKey takes a transaction-level advisory lock on the hash of the key. FOR UPDATE does not lock the rows that do not
+// exist yet, so two transactions would insert the first cookies of a key concurrently.
+func (postgresDialect) LockKey() string {
+	return "SELECT pg_advisory_xact_lock(hashtextextended(?, 0))"
+}
+
+func (postgresDialect) LockMigrations() (string, string) {
+	return "SELECT pg_advisory_lock(hashtextextended('cookiejar_migrations', 0))",
+		"SELECT pg_advisory_unlock(hashtextextended('cookiejar_migrations', 0))"
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string { return "mysql" }
+
+func (mysqlDialect) Rebind(query string) string { return query }
+
+func (mysqlDialect) Upsert(table string, keys, columns []string) string {
+	updates := make([]string, 0, len(columns)-len(keys))
+
+	for _, c := range columns[len(keys):] {
+		updates = append(updates, c+" = VALUES("+c+")")
+	}
+
+	return insert(table, columns) + " ON DUPLICATE KEY UPDATE " + strings.Join(updates, ", ")
+}
+
+func (mysqlDialect) LockRows() string { return "FOR UPDATE" }
+
+// LockKey is empty, as FOR UPDATE also locks the gap of the missing rows with InnoDB.
+func (mysqlDialect) LockKey() string { return "" }
+
+func (mysqlDialect) LockMigrations() (string, string) {
+	return "SELECT GET_LOCK('cookiejar_migrations', -1)", "SELECT RELEASE_LOCK('cookiejar_migrations')"
+}
+
+// onConflictUpsert returns the INSERT ... ON CONFLICT DO UPDATE statement of SQLite and PostgreSQL.
+func onConflictUpsert(table string, keys, columns []string) string {
+	updates := make([]string, 0, len(columns)-len(keys))
+
+	for _, c := range columns[len(keys):] {
+		updates = append(updates, c+" = excluded."+c)
+	}
+
+	return insert(table, columns) + " ON CONFLICT (" + strings.Join(keys, ", ") + ") DO UPDATE SET " +
+		strings.Join(updates, ", ")
+}
+
+func insert(table string, columns []string) string {
+	return "INSERT INTO " + table + " (" + strings.Join(columns, ", ") + ") VALUES (" +
+		strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")"
+}
@@ -0,0 +1,3 @@
+// Package sqlstore stores the entries of cookiejar.Jar in a database/sql database. The dialects of SQLite, PostgreSQL
+// and MySQL are supported, many jars share one table and are told apart by their jar id.
+package sqlstore
@@ -0,0 +1,177 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+)
+
+//go:embed migrations
+var migrations embed.FS
+
+// migration is a schema migration, its version is the number that prefixes its file name.
+type migration struct {
+	version    int
+	name       string
+	statements []string
+}
+
+// Migrate creates or upgrades the cookiejar_cookies table of the dialect. The applied versions are recorded in the
+// cookiejar_migrations table, so that Migrate can be run at every start. The migrations are locked, see
+// Dialect.LockMigrations, so that the processes that start together can all run Migrate.
+func Migrate(ctx context.Context, db *sql.DB, dialect Dialect) (err error) {
+	all, err := readMigrations(dialect)
+	if err != nil {
+		return err
+	}
+
+	// The lock of the migrations is held by a connection.
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("could not connect for migrations: %w", err)
+	}
+
+	defer conn.Close() //nolint: errcheck
+
+	if lock, unlock := dialect.LockMigrations(); lock != "" {
+		if _, err := conn.ExecContext(ctx, lock); err != nil {
+			return fmt.Errorf("could not lock migrations: %w", err)
+		}
+
+		defer func() {
+			if _, unlockErr := conn.ExecContext(context.WithoutCancel(ctx), unlock); unlockErr != nil && err == nil {
+				err = fmt.Errorf("could not unlock migrations: %w", unlockErr)
+			}
+		}()
+	}
+
+	if _, err := conn.ExecContext(ctx,
+		`CREATE TABLE IF NOT EXISTS cookiejar_migrations (version INTEGER NOT NULL PRIMARY KEY)`,
+	); err != nil {
+		return fmt.Errorf("could not create migrations table: %w", err)
+	}
+
+	applied, err := appliedMigrations(ctx, conn)
+	if err != nil {
+		return fmt.Errorf("could not read applied migrations: %w", err)
+	}
+
+	for _, m := range all {
+		if applied[m.version] {
+			continue
+		}
+
+		if err := applyMigration(ctx, conn, dialect, m); err != nil {
+			return fmt.Errorf("could not apply migration %s: %w", m.name, err)
+		}
+	}
+
+	return nil
+}
+
+func appliedMigrations(ctx context.Context, conn *sql.Conn) (map[int]bool, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version FROM cookiejar_migrations`)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close() //nolint: errcheck
+
+	applied := make(map[int]bool)
+
+	for rows.Next() {
+		var version int
+
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+
+		applied[version] = true
+	}
+
+	return applied, rows.Err()
+}
+
+// applyMigration applies m in a transaction, unless another process applied it since the applied versions were read.
+func applyMigration(ctx context.Context, conn *sql.Conn, dialect Dialect, m migration) (err error) {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback() //nolint: errcheck
+		}
+	}()
+
+	var applied int
+
+	if err := tx.QueryRowContext(ctx,
+		dialect.Rebind(`SELECT COUNT(*) FROM cookiejar_migrations WHERE version = ?`), m.version,
+	).Scan(&applied); err != nil {
+		return err
+	}
+
+	if applied > 0 {
+		return tx.Rollback()
+	}
+
+	for _, stmt := range m.statements {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, dialect.Rebind(`INSERT INTO cookiejar_migrations (version) VALUES (?)`),
+		m.version,
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// readMigrations reads the migrations of the dialect, ordered by their version.
+func readMigrations(dialect Dialect) ([]migration, error) {
+	dir := path.Join("migrations", dialect.Name())
+
+	files, err := fs.ReadDir(migrations, dir)
+	if err != nil {
+		return nil, fmt.Errorf("could not read migrations of %s: %w", dialect.Name(), err)
+	}
+
+	result := make([]migration, 0, len(files))
+
+	for _, f := range files {
+		prefix, _, _ := strings.Cut(f.Name(), "_")
+
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version of migration %s: %w", f.Name(), err)
+		}
+
+		data, err := fs.ReadFile(migrations, path.Join(dir, f.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("could not read migration %s: %w", f.Name(), err)
+		}
+
+		m := migration{version: version, name: f.Name()}
+
+		for _, stmt := range strings.Split(string(data), ";") {
+			if stmt = strings.TrimSpace(stmt); stmt != "" {
+				m.statements = append(m.statements, stmt)
+			}
+		}
+
+		result = append(result, m)
+	}
+
+	// fs.ReadDir returns the files sorted by name, the versions are zero-padded.
+	return result, nil
+}
@@ -0,0 +1,23 @@
+CREATE TABLE cookiejar_cookies (
+	jar_id        VARBINARY(64)   NOT NULL,
+	etld1         VARBINARY(255)  NOT NULL,
+	domain        VARBINARY(255)  NOT NULL,
+	path          VARBINARY(1024) NOT NULL,
+	name          VARBINARY(1024) NOT NULL,
+	value         BLOB            NOT NULL,
+	quoted        BOOLEAN         NOT NULL,
+	same_site     VARCHAR(16)     NOT NULL,
+	secure        BOOLEAN         NOT NULL,
+	http_only     BOOLEAN         NOT NULL,
+	persistent    BOOLEAN         NOT NULL,
+	host_only     BOOLEAN         NOT NULL,
+	partitioned   BOOLEAN         NOT NULL,
+	partition_key VARCHAR(255)    NOT NULL,
+	expires       CHAR(30)        NOT NULL,
+	creation      CHAR(30)        NOT NULL,
+	last_access   CHAR(30)        NOT NULL,
+	seq_num       BIGINT UNSIGNED NOT NULL,
+	PRIMARY KEY (jar_id, etld1, domain, path, name)
+);
+
+CREATE INDEX cookiejar_cookies_expires ON cookiejar_cookies (jar_id, persistent, expires);
@@ -0,0 +1,23 @@
+CREATE TABLE cookiejar_cookies (
+	jar_id        TEXT    NOT NULL,
+	etld1         TEXT    NOT NULL,
+	domain        TEXT    NOT NULL,
+	path          TEXT    NOT NULL,
+	name          TEXT    NOT NULL,
+	value         TEXT    NOT NULL,
+	quoted        BOOLEAN NOT NULL,
+	same_site     TEXT    NOT NULL,
+	secure        BOOLEAN NOT NULL,
+	http_only     BOOLEAN NOT NULL,
+	persistent    BOOLEAN NOT NULL,
+	host_only     BOOLEAN NOT NULL,
+	partitioned   BOOLEAN NOT NULL,
+	partition_key TEXT    NOT NULL,
+	expires       TEXT    NOT NULL,
+	creation      TEXT    NOT NULL,
+	last_access   TEXT    NOT NULL,
+	seq_num       BIGINT  NOT NULL,
+	PRIMARY KEY (jar_id, etld1, domain, path, name)
+);
+
+CREATE INDEX cookiejar_cookies_expires ON cookiejar_cookies (jar_id, persistent, expires);
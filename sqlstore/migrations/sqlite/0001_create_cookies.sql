@@ -0,0 +1,23 @@
+CREATE TABLE cookiejar_cookies (
+	jar_id        TEXT    NOT NULL,
+	etld1         TEXT    NOT NULL,
+	domain        TEXT    NOT NULL,
+	path          TEXT    NOT NULL,
+	name          TEXT    NOT NULL,
+	value         TEXT    NOT NULL,
+	quoted        INTEGER NOT NULL,
+	same_site     TEXT    NOT NULL,
+	secure        INTEGER NOT NULL,
+	http_only     INTEGER NOT NULL,
+	persistent    INTEGER NOT NULL,
+	host_only     INTEGER NOT NULL,
+	partitioned   INTEGER NOT NULL,
+	partition_key TEXT    NOT NULL,
+	expires       TEXT    NOT NULL,
+	creation      TEXT    NOT NULL,
+	last_access   TEXT    NOT NULL,
+	seq_num       INTEGER NOT NULL,
+	PRIMARY KEY (jar_id, etld1, domain, path, name)
+);
+
+CREATE INDEX cookiejar_cookies_expires ON cookiejar_cookies (jar_id, persistent, expires);
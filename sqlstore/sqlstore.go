@@ -0,0 +1,185 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.nhat.io/cookiejar"
+	"go.nhat.io/cookiejar/internal/sqlrow"
+)
+
+const table = "cookiejar_cookies"
+
+var (
+	keys    = []string{"jar_id", "etld1", "domain", "path", "name"}
+	columns = []string{
+		"jar_id", "etld1", "domain", "path", "name", "value", "quoted", "same_site", "secure", "http_only", "persistent",
+		"host_only", "partitioned", "partition_key", "expires", "creation", "last_access", "seq_num",
+	}
+)
+
+var (
+	_ cookiejar.Store          = (*Store)(nil)
+	_ cookiejar.ExpiredRemover = (*Store)(nil)
+)
+
+// Store is a cookiejar.Store in a table of a database/sql database.
+//
+// Every entry is a row of the cookiejar_cookies table, keyed by the jar id, eTLD+1, domain, path and name, so that
+// many jars share the table. An update locks the eTLD+1 and only writes the rows that changed, in one transaction. The
+// table is created by Migrate.
+type Store struct {
+	db      *sql.DB
+	dialect Dialect
+	jarID   string
+
+	selectQuery string
+	upsertQuery string
+	deleteQuery string
+}
+
+// New returns the store of the jar with jarID in the database. The schema must be migrated with Migrate before.
+func New(db *sql.DB, dialect Dialect, jarID string) *Store {
+	selectQuery := `SELECT ` + strings.Join(columns[1:], ", ") + ` FROM ` + table + ` WHERE jar_id = ?`
+
+	return &Store{
+		db:          db,
+		dialect:     dialect,
+		jarID:       jarID,
+		selectQuery: selectQuery,
+		upsertQuery: dialect.Rebind(dialect.Upsert(table, keys, columns)),
+		deleteQuery: dialect.Rebind(`DELETE FROM ` + table +
+			` WHERE jar_id = ? AND etld1 = ? AND domain = ? AND path = ? AND name = ?`),
+	}
+}
+
+// Entries returns the entries of key.
+func (s *Store) Entries(key string) (map[string]cookiejar.Entry, error) {
+	entries, err := s.query(context.Background(), s.db, ` AND etld1 = ?`, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return entries[key], nil
+}
+
+// All returns the entries of every key.
+func (s *Store) All() (map[string]map[string]cookiejar.Entry, error) {
+	return s.query(context.Background(), s.db, "")
+}
+
+// Update locks key, calls fn with its entries and writes the upserted and deleted entries in one transaction.
+func (s *Store) Update(key string, fn func(entries map[string]cookiejar.Entry) bool) (err error) {
+	ctx := context.Background()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("could not begin cookies transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback() //nolint: errcheck
+		}
+	}()
+
+	if lock := s.dialect.LockKey(); lock != "" {
+		if _, err := tx.ExecContext(ctx, s.dialect.Rebind(lock), s.jarID+";"+key); err != nil {
+			return fmt.Errorf("could not lock cookies of %q: %w", key, err)
+		}
+	}
+
+	all, err := s.query(ctx, tx, ` AND etld1 = ? `+s.dialect.LockRows(), key)
+	if err != nil {
+		return err
+	}
+
+	before := all[key]
+	after := make(map[string]cookiejar.Entry, len(before))
+
+	for id, e := range before {
+		after[id] = e
+	}
+
+	if !fn(after) {
+		return tx.Rollback()
+	}
+
+	upserts, deletes := cookiejar.DiffEntries(before, after)
+
+	for _, e := range deletes {
+		if _, err := tx.ExecContext(ctx, s.deleteQuery, s.jarID, key, e.Domain, e.Path, e.Name); err != nil {
+			return fmt.Errorf("could not delete cookie: %w", err)
+		}
+	}
+
+	for _, e := range upserts {
+		if _, err := tx.ExecContext(ctx, s.upsertQuery, sqlrow.Values(e, s.jarID, key)...); err != nil {
+			return fmt.Errorf("could not upsert cookie: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("could not commit cookies transaction: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveExpired deletes the persistent entries of the jar that expire at or before now and returns them. The expired
+// rows are locked and deleted one by one in a transaction, as not every dialect returns the deleted rows.
+func (s *Store) RemoveExpired(now time.Time) (removed map[string]map[string]cookiejar.Entry, err error) {
+	ctx := context.Background()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not begin cookies transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback() //nolint: errcheck
+		}
+	}()
+
+	removed, err = s.query(ctx, tx, ` AND persistent = ? AND expires <= ? `+s.dialect.LockRows(),
+		true, sqlrow.FormatTime(now),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, entries := range removed {
+		for _, e := range entries {
+			if _, err := tx.ExecContext(ctx, s.deleteQuery, s.jarID, key, e.Domain, e.Path, e.Name); err != nil {
+				return nil, fmt.Errorf("could not remove expired cookie: %w", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("could not commit cookies transaction: %w", err)
+	}
+
+	return removed, nil
+}
+
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// query reads the entries of the jar that match the condition, grouped by their eTLD+1.
+func (s *Store) query(ctx context.Context, q querier, cond string, args ...any,
+) (map[string]map[string]cookiejar.Entry, error) {
+	rows, err := q.QueryContext(ctx, s.dialect.Rebind(s.selectQuery+cond), append([]any{s.jarID}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("could not query cookies: %w", err)
+	}
+
+	defer rows.Close() //nolint: errcheck
+
+	return sqlrow.Scan(rows)
+}
@@ -0,0 +1,207 @@
+package sqlstore_test
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.nhat.io/cookiejar"
+	"go.nhat.io/cookiejar/sqlstore"
+
+	_ "modernc.org/sqlite" // Pure-Go SQLite driver.
+)
+
+func openDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db := openEmptyDB(t)
+
+	// Migrate can be run at every start.
+	require.NoError(t, sqlstore.Migrate(context.Background(), db, sqlstore.SQLite))
+	require.NoError(t, sqlstore.Migrate(context.Background(), db, sqlstore.SQLite))
+
+	return db
+}
+
+func openEmptyDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dsn := (&url.URL{
+		Scheme:   "file",
+		Path:     filepath.ToSlash(filepath.Join(t.TempDir(), "cookies.db")),
+		RawQuery: "_txlock=immediate&_pragma=busy_timeout(5000)",
+	}).String()
+
+	db, err := sql.Open("sqlite", dsn)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	return db
+}
+
+func TestStore(t *testing.T) {
+	t.Parallel()
+
+	db := openDB(t)
+	u := &url.URL{Scheme: "https", Host: "www.example.com", Path: "/"}
+
+	alice, err := cookiejar.New(&cookiejar.Options{Store: sqlstore.New(db, sqlstore.SQLite, "alice")})
+	require.NoError(t, err)
+
+	bob, err := cookiejar.New(&cookiejar.Options{Store: sqlstore.New(db, sqlstore.SQLite, "bob")})
+	require.NoError(t, err)
+
+	alice.SetCookies(u, []*http.Cookie{
+		{Name: "id", Value: "alice", SameSite: http.SameSiteLaxMode},
+		{Name: "theme", Value: "dark", Domain: "example.com", MaxAge: 3600},
+	})
+	bob.SetCookies(u, []*http.Cookie{{Name: "id", Value: "bob"}})
+
+	// The jars share the table but not the cookies.
+	assert.Equal(t, []*http.Cookie{{Name: "id", Value: "alice"}, {Name: "theme", Value: "dark"}}, alice.Cookies(u))
+	assert.Equal(t, []*http.Cookie{{Name: "id", Value: "bob"}}, bob.Cookies(u))
+
+	reopened, err := cookiejar.New(&cookiejar.Options{Store: sqlstore.New(db, sqlstore.SQLite, "alice")})
+	require.NoError(t, err)
+
+	entries := reopened.Entries()
+
+	require.Len(t, entries, 2)
+	assert.Equal(t, "SameSite=Lax", entries[0].SameSite)
+	assert.True(t, entries[1].Persistent)
+
+	reopened.SetCookies(u, []*http.Cookie{{Name: "id", MaxAge: -1}})
+
+	assert.Equal(t, []*http.Cookie{{Name: "theme", Value: "dark"}}, alice.Cookies(u))
+	assert.Equal(t, []*http.Cookie{{Name: "id", Value: "bob"}}, bob.Cookies(u))
+
+	var count int
+
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM cookiejar_cookies`).Scan(&count))
+	assert.Equal(t, 2, count)
+}
+
+func TestStore_RemoveExpired(t *testing.T) {
+	t.Parallel()
+
+	db := openDB(t)
+	expiring := cookiejar.Entry{
+		Name: "expiring", Value: "1", Domain: "example.com", Path: "/", HostOnly: true,
+		Persistent: true, Expires: time.Now().Add(50 * time.Millisecond),
+	}
+
+	store := sqlstore.New(db, sqlstore.SQLite, "alice")
+
+	alice, err := cookiejar.New(&cookiejar.Options{Store: store})
+	require.NoError(t, err)
+
+	bob, err := cookiejar.New(&cookiejar.Options{Store: sqlstore.New(db, sqlstore.SQLite, "bob")})
+	require.NoError(t, err)
+
+	alice.ImportEntries(expiring, cookiejar.Entry{Name: "session", Value: "1", Domain: "example.com", Path: "/"})
+	bob.ImportEntries(expiring)
+
+	time.Sleep(100 * time.Millisecond)
+
+	removed, err := store.RemoveExpired(time.Now())
+	require.NoError(t, err)
+
+	// The removed entries are returned to be audited.
+	require.Len(t, removed, 1)
+	assert.Equal(t, "1", removed["example.com"]["example.com;/;expiring"].Value)
+
+	var count int
+
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM cookiejar_cookies WHERE jar_id = 'alice'`).Scan(&count))
+	assert.Equal(t, 1, count)
+
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM cookiejar_cookies WHERE jar_id = 'bob'`).Scan(&count))
+	assert.Equal(t, 1, count)
+}
+
+func TestMigrate_Concurrent(t *testing.T) {
+	t.Parallel()
+
+	db := openEmptyDB(t)
+
+	var wg sync.WaitGroup
+
+	errs := make([]error, 4)
+
+	for i := range errs {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			errs[i] = sqlstore.Migrate(context.Background(), db, sqlstore.SQLite)
+		}()
+	}
+
+	wg.Wait()
+
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+
+	var count int
+
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM cookiejar_migrations`).Scan(&count))
+	assert.Equal(t, 1, count)
+}
+
+func TestDialect(t *testing.T) {
+	t.Parallel()
+
+	keys := []string{"id"}
+	columns := []string{"id", "a", "b"}
+
+	testCases := []struct {
+		scenario       string
+		dialect        sqlstore.Dialect
+		expectedRebind string
+		expectedUpsert string
+		expectedLock   string
+	}{
+		{
+			scenario:       "sqlite",
+			dialect:        sqlstore.SQLite,
+			expectedRebind: "a = ? AND b = ?",
+			expectedUpsert: "INSERT INTO t (id, a, b) VALUES (?, ?, ?) ON CONFLICT (id) DO UPDATE SET a = excluded.a, b = excluded.b",
+		},
+		{
+			scenario:       "postgres",
+			dialect:        sqlstore.Postgres,
+			expectedRebind: "a = $1 AND b = $2",
+			expectedUpsert: "INSERT INTO t (id, a, b) VALUES (?, ?, ?) ON CONFLICT (id) DO UPDATE SET a = excluded.a, b = excluded.b",
+			expectedLock:   "SELECT pg_advisory_xact_lock(hashtextextended(?, 0))",
+		},
+		{
+			scenario:       "mysql",
+			dialect:        sqlstore.MySQL,
+			expectedRebind: "a = ? AND b = ?",
+			expectedUpsert: "INSERT INTO t (id, a, b) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE a = VALUES(a), b = VALUES(b)",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expectedRebind, tc.dialect.Rebind("a = ? AND b = ?"))
+			assert.Equal(t, tc.expectedUpsert, tc.dialect.Upsert("t", keys, columns))
+			assert.Equal(t, tc.expectedLock, tc.dialect.LockKey())
+		})
+	}
+}
@@ -0,0 +1,327 @@
+package cookiejar
+
+import (
+	"maps"
+	"slices"
+	"time"
+)
+
+// Store is the storage of the entries of a Jar. The entries are grouped by their key, the eTLD+1 of their domain, and
+// are keyed by their Entry.ID in a group, as in the in-memory storage of the Jar.
+//
+// The Jar serializes its own calls, but a Store may be shared by several jars, in the same or in other processes.
+type Store interface {
+	// Entries returns the entries of key. A missing key has no entries.
+	Entries(key string) (map[string]Entry, error)
+
+	// All returns the entries of every key.
+	All() (map[string]map[string]Entry, error)
+
+	// Update atomically reads the entries of key, calls fn to modify them in place and, if fn reports a
+	// modification, stores the result. An empty result removes the key. The fn may be called more than once when the
+	// update has to be retried.
+	Update(key string, fn func(entries map[string]Entry) bool) error
+}
+
+// ExpiredRemover is implemented by a Store that removes the expired entries by itself, without reading them all. It
+// returns the removed entries keyed by their eTLD+1 and their Entry.ID, so that their removal is audited.
+type ExpiredRemover interface {
+	RemoveExpired(now time.Time) (map[string]map[string]Entry, error)
+}
+
+// DiffEntries returns the entries of after that are new or changed, and the entries of before that are removed, so
+// that a Store can apply an update incrementally.
+func DiffEntries(before, after map[string]Entry) (upserts, deletes []Entry) {
+	for id, e := range after {
+		if old, ok := before[id]; !ok || !sameEntry(old, e) {
+			upserts = append(upserts, e)
+		}
+	}
+
+	for id, e := range before {
+		if _, ok := after[id]; !ok {
+			deletes = append(deletes, e)
+		}
+	}
+
+	return upserts, deletes
+}
+
+// onlyAccessed reports whether b only differs from a by its last access time.
+func onlyAccessed(a, b Entry) bool {
+	a.LastAccess = b.LastAccess
+
+	return sameEntry(a, b)
+}
+
+// sameEntry reports whether a and b are equal, comparing the times with Equal.
+func sameEntry(a, b Entry) bool {
+	return a.Expires.Equal(b.Expires) && a.Creation.Equal(b.Creation) && a.LastAccess.Equal(b.LastAccess) &&
+		a.Name == b.Name && a.Value == b.Value && a.Quoted == b.Quoted && a.Domain == b.Domain && a.Path == b.Path &&
+		a.SameSite == b.SameSite && a.Secure == b.Secure && a.HttpOnly == b.HttpOnly && a.Persistent == b.Persistent &&
+		a.HostOnly == b.HostOnly && a.Partitioned == b.Partitioned && a.PartitionKey == b.PartitionKey &&
+		a.SeqNum == b.SeqNum
+}
+
+// RemoveExpired removes the expired entries from the jar. Without a Store, the expired entries are otherwise removed
+// only when the cookies of their key are read. With a Store, it also writes the last access times of the cookies sent
+// by Cookies, which are otherwise written with the next change of their key.
+func (j *Jar) RemoveExpired() error {
+	return j.removeExpired(time.Now())
+}
+
+// removeExpired is like RemoveExpired but takes the current time as a parameter.
+func (j *Jar) removeExpired(now time.Time) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if err := j.flushAccessed(); err != nil {
+		return err
+	}
+
+	if r, ok := j.store.(ExpiredRemover); ok {
+		removed, err := r.RemoveExpired(now)
+		if err != nil {
+			return err
+		}
+
+		if j.audit != nil {
+			j.emitAudit(j.auditExpired(removed, now))
+		}
+
+		return nil
+	}
+
+	keys, err := j.keys()
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		err := j.updateSubmapErr(key, "", now, func(submap map[string]entry) bool {
+			modified := false
+
+			for id, e := range submap {
+				if e.Persistent && !e.Expires.After(now) {
+					delete(submap, id)
+
+					modified = true
+				}
+			}
+
+			return modified
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// keys returns the keys of the jar. The caller must hold j.mu.
+func (j *Jar) keys() ([]string, error) {
+	if j.store == nil {
+		return sortedKeys(j.entries), nil
+	}
+
+	all, err := j.store.All()
+	if err != nil {
+		return nil, err
+	}
+
+	return sortedKeys(all), nil
+}
+
+// updateSubmap calls fn with the entries of key and stores them if fn reports a modification. The changes are audited
+// with source as their URL. The errors of the Store are passed to the StoreErrorHandler. The caller must hold j.mu.
+func (j *Jar) updateSubmap(key, source string, now time.Time, fn func(submap map[string]entry) bool) {
+	if err := j.updateSubmapErr(key, source, now, fn); err != nil {
+		j.storeError(err)
+	}
+}
+
+// updateSubmapErr is like updateSubmap but returns the errors of the Store.
+func (j *Jar) updateSubmapErr(key, source string, now time.Time, fn func(submap map[string]entry) bool) error {
+	if j.audit == nil {
+		return j.storeSubmap(key, fn)
+	}
+
+	var records []AuditRecord
+
+	err := j.storeSubmap(key, func(submap map[string]entry) bool {
+		before := maps.Clone(submap)
+
+		// fn is called again when the Store retries the update.
+		records = nil
+
+		if !fn(submap) {
+			return false
+		}
+
+		records = j.auditRecords(before, submap, source, now)
+
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	j.emitAudit(records)
+
+	return nil
+}
+
+// storeSubmap calls fn with the entries of key and stores them in memory or in the Store if fn reports a modification.
+func (j *Jar) storeSubmap(key string, fn func(submap map[string]entry) bool) error {
+	if j.store == nil {
+		submap := j.entries[key]
+		if submap == nil {
+			submap = make(map[string]entry)
+		}
+
+		if !fn(submap) {
+			return nil
+		}
+
+		if len(submap) == 0 {
+			delete(j.entries, key)
+		} else {
+			j.entries[key] = submap
+		}
+
+		return nil
+	}
+
+	stored := false
+
+	err := j.store.Update(key, func(entries map[string]Entry) bool {
+		submap := j.importSubmap(key, entries)
+
+		if stored = fn(submap); !stored {
+			return false
+		}
+
+		clear(entries)
+
+		for id, e := range submap {
+			entries[id] = exportEntry(e)
+		}
+
+		return true
+	})
+
+	// The pending last access times of key are written with the entries.
+	if err == nil && stored {
+		delete(j.accessed, key)
+	}
+
+	return err
+}
+
+// markAccessed keeps the last access time of the entries of key that are sent, until the next update of key or
+// flushAccessed writes them. Cookies does not write to the Store when no cookie is removed, so that the reads do not
+// take the write lock of the Store. The caller must hold j.mu.
+func (j *Jar) markAccessed(key string, selected []entry, now time.Time) {
+	if j.accessed == nil {
+		j.accessed = make(map[string]map[string]time.Time)
+	}
+
+	if j.accessed[key] == nil {
+		j.accessed[key] = make(map[string]time.Time, len(selected))
+	}
+
+	for _, e := range selected {
+		j.accessed[key][e.id()] = now
+	}
+}
+
+// flushAccessed writes the pending last access times to the Store. The caller must hold j.mu.
+func (j *Jar) flushAccessed() error {
+	for _, key := range sortedKeys(j.accessed) {
+		err := j.storeSubmap(key, func(submap map[string]entry) bool {
+			for id := range j.accessed[key] {
+				if _, ok := submap[id]; ok {
+					return true
+				}
+			}
+
+			return false
+		})
+		if err != nil {
+			return err
+		}
+
+		// The entries that were removed since they were sent have no last access time to write.
+		delete(j.accessed, key)
+	}
+
+	return nil
+}
+
+// submap returns the entries of key. The errors of the Store are passed to the StoreErrorHandler. The caller must
+// hold j.mu and must not modify the result.
+func (j *Jar) submap(key string) map[string]entry {
+	if j.store == nil {
+		return j.entries[key]
+	}
+
+	entries, err := j.store.Entries(key)
+	if err != nil {
+		j.storeError(err)
+
+		return nil
+	}
+
+	return j.importSubmap(key, entries)
+}
+
+// allEntries returns the entries of every key. The caller must hold j.mu and must not modify the result.
+func (j *Jar) allEntries() (map[string]map[string]entry, error) {
+	if j.store == nil {
+		return j.entries, nil
+	}
+
+	all, err := j.store.All()
+	if err != nil {
+		return nil, err
+	}
+
+	imported := make(map[string]map[string]entry, len(all))
+
+	for key, entries := range all {
+		imported[key] = j.importSubmap(key, entries)
+	}
+
+	return imported, nil
+}
+
+// importSubmap converts the entries of key read from the Store, with their pending last access time, and moves the next
+// sequence number past them, so that the new entries sort after the stored ones.
+func (j *Jar) importSubmap(key string, entries map[string]Entry) map[string]entry {
+	submap := make(map[string]entry, len(entries))
+
+	for id, e := range entries {
+		if accessed, ok := j.accessed[key][id]; ok && accessed.After(e.LastAccess) {
+			e.LastAccess = accessed
+		}
+
+		submap[id] = importEntry(e)
+
+		if e.SeqNum >= j.nextSeqNum {
+			j.nextSeqNum = e.SeqNum + 1
+		}
+	}
+
+	return submap
+}
+
+func (j *Jar) storeError(err error) {
+	if j.onStoreError != nil {
+		j.onStoreError(err)
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	return slices.Sorted(maps.Keys(m))
+}
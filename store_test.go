@@ -0,0 +1,275 @@
+package cookiejar_test
+
+import (
+	"errors"
+	"maps"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.nhat.io/cookiejar"
+)
+
+// mapStore is a cookiejar.Store in a map.
+type mapStore struct {
+	mu      sync.Mutex
+	entries map[string]map[string]cookiejar.Entry
+	err     error
+	writes  int
+}
+
+func (s *mapStore) Entries(key string) (map[string]cookiejar.Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return maps.Clone(s.entries[key]), s.err
+}
+
+func (s *mapStore) All() (map[string]map[string]cookiejar.Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all := make(map[string]map[string]cookiejar.Entry, len(s.entries))
+
+	for key, entries := range s.entries {
+		all[key] = maps.Clone(entries)
+	}
+
+	return all, s.err
+}
+
+func (s *mapStore) Update(key string, fn func(entries map[string]cookiejar.Entry) bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+
+	entries := maps.Clone(s.entries[key])
+	if entries == nil {
+		entries = make(map[string]cookiejar.Entry)
+	}
+
+	if !fn(entries) {
+		return nil
+	}
+
+	s.writes++
+
+	if len(entries) == 0 {
+		delete(s.entries, key)
+	} else {
+		s.entries[key] = entries
+	}
+
+	return nil
+}
+
+func TestJar_Store(t *testing.T) {
+	t.Parallel()
+
+	store := &mapStore{entries: make(map[string]map[string]cookiejar.Entry)}
+	u := &url.URL{Scheme: "https", Host: "www.example.com", Path: "/"}
+
+	jar, err := cookiejar.New(&cookiejar.Options{Store: store})
+	require.NoError(t, err)
+
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "id", Value: "42"},
+		{Name: "theme", Value: "dark", Domain: "example.com", MaxAge: 3600},
+	})
+
+	require.Len(t, store.entries["example.com"], 2)
+
+	// Another jar sees the cookies of the shared store and numbers its new cookies after them.
+	other, err := cookiejar.New(&cookiejar.Options{Store: store})
+	require.NoError(t, err)
+
+	other.SetCookies(u, []*http.Cookie{{Name: "lang", Value: "en"}})
+
+	expected := []*http.Cookie{
+		{Name: "id", Value: "42"},
+		{Name: "theme", Value: "dark"},
+		{Name: "lang", Value: "en"},
+	}
+
+	assert.Equal(t, expected, jar.Cookies(u))
+	assert.Equal(t, uint64(2), store.entries["example.com"]["www.example.com;/;lang"].SeqNum)
+
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "id", MaxAge: -1},
+		{Name: "theme", Domain: "example.com", MaxAge: -1},
+		{Name: "lang", MaxAge: -1},
+	})
+
+	assert.Empty(t, store.entries)
+	assert.Empty(t, other.Entries())
+}
+
+func TestJar_Store_Access(t *testing.T) {
+	t.Parallel()
+
+	store := &mapStore{entries: make(map[string]map[string]cookiejar.Entry)}
+	u := &url.URL{Scheme: "https", Host: "example.com", Path: "/"}
+
+	jar, err := cookiejar.New(&cookiejar.Options{Store: store})
+	require.NoError(t, err)
+
+	jar.SetCookies(u, []*http.Cookie{{Name: "id", Value: "42"}})
+
+	created := store.entries["example.com"]["example.com;/;id"].LastAccess
+
+	time.Sleep(10 * time.Millisecond)
+
+	// Reading the cookies does not write to the store.
+	for range 3 {
+		assert.Equal(t, []*http.Cookie{{Name: "id", Value: "42"}}, jar.Cookies(u))
+		assert.Empty(t, jar.Cookies(&url.URL{Scheme: "https", Host: "example.org", Path: "/"}))
+	}
+
+	assert.Equal(t, 1, store.writes)
+	assert.Equal(t, created, store.entries["example.com"]["example.com;/;id"].LastAccess)
+
+	// The jar sees the last access time that is not written yet.
+	entries := jar.Entries()
+
+	require.Len(t, entries, 1)
+	assert.True(t, entries[0].LastAccess.After(created))
+
+	// The last access times are written by RemoveExpired.
+	require.NoError(t, jar.RemoveExpired())
+
+	assert.Equal(t, 2, store.writes)
+	assert.True(t, entries[0].LastAccess.Equal(store.entries["example.com"]["example.com;/;id"].LastAccess))
+
+	require.NoError(t, jar.RemoveExpired())
+
+	assert.Equal(t, 2, store.writes)
+
+	// An expired cookie is removed when the cookies are read.
+	jar.ImportEntries(cookiejar.Entry{
+		Name:       "expiring",
+		Value:      "1",
+		Domain:     "example.com",
+		Path:       "/",
+		HostOnly:   true,
+		Persistent: true,
+		Expires:    time.Now().Add(10 * time.Millisecond),
+	})
+
+	time.Sleep(20 * time.Millisecond)
+
+	assert.Equal(t, []*http.Cookie{{Name: "id", Value: "42"}}, jar.Cookies(u))
+	assert.Equal(t, 4, store.writes)
+	assert.NotContains(t, store.entries["example.com"], "example.com;/;expiring")
+}
+
+func TestJar_StoreErrorHandler(t *testing.T) {
+	t.Parallel()
+
+	storeErr := errors.New("store error")
+	store := &mapStore{entries: make(map[string]map[string]cookiejar.Entry), err: storeErr}
+
+	var actual []error
+
+	jar, err := cookiejar.New(&cookiejar.Options{
+		Store:             store,
+		StoreErrorHandler: func(err error) { actual = append(actual, err) },
+	})
+	require.NoError(t, err)
+
+	u := &url.URL{Scheme: "https", Host: "example.com"}
+
+	jar.SetCookies(u, []*http.Cookie{{Name: "id", Value: "42"}})
+
+	assert.Empty(t, jar.Cookies(u))
+	assert.Nil(t, jar.Entries())
+	assert.ErrorIs(t, jar.RemoveExpired(), storeErr)
+	assert.Equal(t, []error{storeErr, storeErr, storeErr}, actual)
+}
+
+func TestJar_RemoveExpired(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario string
+		options  *cookiejar.Options
+	}{
+		{
+			scenario: "in memory",
+		},
+		{
+			scenario: "with store",
+			options:  &cookiejar.Options{Store: &mapStore{entries: make(map[string]map[string]cookiejar.Entry)}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			jar, err := cookiejar.New(tc.options)
+			require.NoError(t, err)
+
+			jar.ImportEntries(
+				cookiejar.Entry{Name: "session", Value: "1", Domain: "example.com", Path: "/", HostOnly: true},
+				cookiejar.Entry{
+					Name: "expiring", Value: "1", Domain: "example.com", Path: "/", HostOnly: true,
+					Persistent: true, Expires: time.Now().Add(50 * time.Millisecond),
+				},
+				cookiejar.Entry{
+					Name: "expiring", Value: "1", Domain: "example.org", Path: "/", HostOnly: true,
+					Persistent: true, Expires: time.Now().Add(50 * time.Millisecond),
+				},
+			)
+
+			require.Len(t, jar.Entries(), 3)
+
+			time.Sleep(100 * time.Millisecond)
+
+			require.NoError(t, jar.RemoveExpired())
+
+			entries := jar.Entries()
+
+			require.Len(t, entries, 1)
+			assert.Equal(t, "session", entries[0].Name)
+		})
+	}
+}
+
+func TestDiffEntries(t *testing.T) {
+	t.Parallel()
+
+	creation := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	kept := cookiejar.Entry{Name: "kept", Domain: "example.com", Path: "/", Creation: creation}
+	changed := cookiejar.Entry{Name: "changed", Value: "1", Domain: "example.com", Path: "/"}
+	removed := cookiejar.Entry{Name: "removed", Domain: "example.com", Path: "/"}
+	added := cookiejar.Entry{Name: "added", Domain: "example.com", Path: "/"}
+
+	before := map[string]cookiejar.Entry{
+		"example.com;/;kept":    kept,
+		"example.com;/;changed": changed,
+		"example.com;/;removed": removed,
+	}
+
+	changed.Value = "2"
+	kept.Creation = creation.In(time.FixedZone("UTC+1", 3600))
+
+	after := map[string]cookiejar.Entry{
+		"example.com;/;kept":    kept,
+		"example.com;/;changed": changed,
+		"example.com;/;added":   added,
+	}
+
+	upserts, deletes := cookiejar.DiffEntries(before, after)
+
+	assert.ElementsMatch(t, []cookiejar.Entry{changed, added}, upserts)
+	assert.Equal(t, []cookiejar.Entry{removed}, deletes)
+}